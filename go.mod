@@ -1,6 +1,6 @@
 module github.com/mimir-news/mimir-go
 
-go 1.12
+go 1.13
 
 require (
 	github.com/gin-gonic/gin v1.4.1-0.20190710050240-502c898d755b
@@ -0,0 +1,233 @@
+package httpclient
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// TokenSource provides the access tokens a client authenticates with.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx stdcontext.Context) (string, error)
+	// Invalidate discards a token rejected by a downstream service.
+	Invalidate(token string)
+}
+
+// AuthMode decides which token a client sends.
+type AuthMode int
+
+// Auth modes.
+const (
+	// ForwardUserToken forwards the auth token of the call context, the default.
+	ForwardUserToken AuthMode = iota
+	// ServiceToken always sends the token of the token source.
+	ServiceToken
+	// ServiceTokenFallback forwards the auth token of the call context if
+	// present and sends the token of the token source otherwise, e.g. for
+	// background jobs using context.NewBackground.
+	ServiceTokenFallback
+)
+
+type staticToken string
+
+// StaticToken creates a token source always returning the same token.
+func StaticToken(token string) TokenSource {
+	return staticToken(token)
+}
+
+func (t staticToken) Token(ctx stdcontext.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t staticToken) Invalidate(token string) {}
+
+// DefaultRefreshBefore is how long before expiry a client credentials token is refreshed unless configured.
+// The margin is capped at half the token lifetime, so that short lived tokens are still cached.
+const DefaultRefreshBefore = time.Minute
+
+// DefaultTokenLifetime is the assumed lifetime of tokens issued without expires_in.
+const DefaultTokenLifetime = 5 * time.Minute
+
+// ClientCredentialsConfig configures an OAuth2 client credentials token source.
+type ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RefreshBefore defaults to DefaultRefreshBefore.
+	RefreshBefore time.Duration
+	// HTTPClient used to fetch tokens, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type clientCredentials struct {
+	cfg ClientCredentialsConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+}
+
+// NewClientCredentials creates a token source fetching tokens with the OAuth2 client credentials
+// grant. Tokens are cached and refreshed before they expire. A token source may be shared between clients.
+func NewClientCredentials(cfg ClientCredentialsConfig) TokenSource {
+	if cfg.RefreshBefore <= 0 {
+		cfg.RefreshBefore = DefaultRefreshBefore
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	return &clientCredentials{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Token returns the cached token, fetching a new one if missing or about to expire.
+func (cc *clientCredentials) Token(ctx stdcontext.Context) (string, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.token != "" && cc.now().Before(cc.refreshAt) {
+		return cc.token, nil
+	}
+
+	token, expiresIn, err := cc.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	cc.token = token
+	cc.refreshAt = cc.now().Add(cc.refreshIn(expiresIn))
+	return token, nil
+}
+
+// refreshIn calculates when a token with the lifetime should be refreshed, refreshing
+// at the latest halfway through the lifetime.
+func (cc *clientCredentials) refreshIn(expiresIn time.Duration) time.Duration {
+	if expiresIn <= 0 {
+		expiresIn = DefaultTokenLifetime
+	}
+
+	margin := cc.cfg.RefreshBefore
+	if margin > expiresIn/2 {
+		margin = expiresIn / 2
+	}
+
+	return expiresIn - margin
+}
+
+func (cc *clientCredentials) Invalidate(token string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.token == token {
+		cc.token = ""
+	}
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (cc *clientCredentials) fetch(ctx stdcontext.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cc.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cc.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cc.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(url.QueryEscape(cc.cfg.ClientID), url.QueryEscape(cc.cfg.ClientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := cc.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer res.Body.Close()
+
+	var body tokenResponse
+	err = json.NewDecoder(io.LimitReader(res.Body, maxErrorBodySize)).Decode(&body)
+	io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token request failed with status %d: %s %s", res.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if body.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response without access_token")
+	}
+
+	return body.AccessToken, time.Duration(body.ExpiresIn) * time.Second, nil
+}
+
+// authenticate sets the token of the token source as the Authorization header according
+// to the auth mode. A request rejected with 401 is retried once with a fresh token.
+func (c *client) authenticate(call *Call, next Invoker) (*http.Response, error) {
+	if c.authMode == ForwardUserToken || (c.authMode == ServiceTokenFallback && call.Ctx.AuthToken != "") {
+		return next(call)
+	}
+
+	token, err := c.setServiceToken(call)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := next(call)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	if call.Request.Body != nil && call.Request.GetBody == nil {
+		return res, nil
+	}
+
+	req, err := rewindRequest(call.Request)
+	if err != nil {
+		return res, nil
+	}
+
+	drainAndClose(res)
+	c.tokenSource.Invalidate(token)
+	call.Request = req
+	_, err = c.setServiceToken(call)
+	if err != nil {
+		return nil, err
+	}
+
+	return next(call)
+}
+
+func (c *client) setServiceToken(call *Call) (string, error) {
+	token, err := c.tokenSource.Token(call.Request.Context())
+	if err != nil {
+		log.Errorw("Failed to get service token", "client", c.name, "requestId", call.Ctx.ID, "error", err)
+		message := fmt.Sprintf("Failed to get service token for downstream service %s. requestId=[%s]", c.name, call.Ctx.ID)
+		return "", httputil.BadGateway(message)
+	}
+
+	call.Request.Header.Set("Authorization", "Bearer "+token)
+	return token, nil
+}
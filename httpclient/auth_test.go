@@ -0,0 +1,153 @@
+package httpclient
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, ok := r.BasicAuth()
+		assert.True(ok)
+		assert.Equal("news-service", clientID)
+		assert.Equal("secret", secret)
+		assert.NoError(r.ParseForm())
+		assert.Equal("client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal("stocks:read tweets:read", r.PostForm.Get("scope"))
+
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	revoked := "token-1"
+	var rejectAll int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if auth == "Bearer "+revoked || atomic.LoadInt32(&rejectAll) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Authorization", auth)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	source := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "news-service",
+		ClientSecret: "secret",
+		Scopes:       []string{"stocks:read", "tweets:read"},
+	})
+	c := NewClient("auth-test", server.URL, WithTokenSource(source, ServiceTokenFallback))
+	background := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Get(background, "/v1/stocks")
+	assert.NoError(err)
+	assert.Equal("Bearer token-2", res.Header.Get("X-Authorization"))
+	assert.Equal(int32(2), atomic.LoadInt32(&issued))
+
+	res, err = c.Get(background, "/v1/stocks")
+	assert.NoError(err)
+	assert.Equal("Bearer token-2", res.Header.Get("X-Authorization"))
+	assert.Equal(int32(2), atomic.LoadInt32(&issued))
+
+	user := context.NewBackground("client-id", "sv", "user-token")
+	res, err = c.Get(user, "/v1/stocks")
+	assert.NoError(err)
+	assert.Equal("Bearer user-token", res.Header.Get("X-Authorization"))
+
+	c = NewClient("auth-test", server.URL, WithTokenSource(source, ServiceToken))
+	res, err = c.Get(user, "/v1/stocks")
+	assert.NoError(err)
+	assert.Equal("Bearer token-2", res.Header.Get("X-Authorization"))
+
+	revoked = "token-2"
+	res, err = c.Get(user, "/v1/stocks")
+	assert.NoError(err)
+	assert.Equal("Bearer token-3", res.Header.Get("X-Authorization"))
+
+	atomic.StoreInt32(&rejectAll, 1)
+	_, err = c.Get(user, "/v1/stocks")
+	var remoteErr *RemoteError
+	assert.True(errors.As(err, &remoteErr))
+	assert.Equal(http.StatusUnauthorized, remoteErr.StatusCode)
+	assert.Equal(int32(4), atomic.LoadInt32(&issued))
+}
+
+func TestClientCredentialsTokenLifetime(t *testing.T) {
+	assert := assert.New(t)
+
+	var issued int32
+	var expiresIn string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer"%s}`, n, expiresIn)
+	}))
+	defer tokenServer.Close()
+
+	now := time.Now()
+	newSource := func() *clientCredentials {
+		atomic.StoreInt32(&issued, 0)
+		source := NewClientCredentials(ClientCredentialsConfig{TokenURL: tokenServer.URL}).(*clientCredentials)
+		source.now = func() time.Time { return now }
+		return source
+	}
+	token := func(source *clientCredentials) string {
+		token, err := source.Token(stdcontext.Background())
+		assert.NoError(err)
+		return token
+	}
+
+	// Without expires_in the default lifetime is assumed.
+	expiresIn = ""
+	source := newSource()
+	assert.Equal("token-1", token(source))
+	now = now.Add(DefaultTokenLifetime - DefaultRefreshBefore - time.Second)
+	assert.Equal("token-1", token(source))
+	now = now.Add(2 * time.Second)
+	assert.Equal("token-2", token(source))
+
+	// Tokens shorter lived than the refresh margin are refreshed halfway through their lifetime.
+	expiresIn = `,"expires_in":60`
+	source = newSource()
+	assert.Equal("token-1", token(source))
+	now = now.Add(29 * time.Second)
+	assert.Equal("token-1", token(source))
+	now = now.Add(2 * time.Second)
+	assert.Equal("token-2", token(source))
+	assert.Equal(int32(2), atomic.LoadInt32(&issued))
+}
+
+func TestTokenSourceFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer tokenServer.Close()
+
+	source := NewClientCredentials(ClientCredentialsConfig{TokenURL: tokenServer.URL})
+	c := NewClient("auth-test", "http://localhost:1", WithTokenSource(source, ServiceToken))
+
+	_, err := c.Get(context.NewBackground("client-id", "sv", ""), "/v1/stocks")
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+}
@@ -0,0 +1,258 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Strategy decides which endpoint a load balancer sends a request to.
+type Strategy int
+
+// Load balancing strategies.
+const (
+	RoundRobin Strategy = iota
+	LeastInFlight
+)
+
+// ErrNoEndpoints returned when an endpoint source provides no endpoints.
+var ErrNoEndpoints = errors.New("no endpoints found")
+
+// EndpointSource provides the base urls of the replicas of a downstream service.
+type EndpointSource interface {
+	Endpoints() ([]string, error)
+}
+
+// StaticEndpoints is a fixed list of base urls.
+type StaticEndpoints []string
+
+// Endpoints returns the list of base urls.
+func (e StaticEndpoints) Endpoints() ([]string, error) {
+	return e, nil
+}
+
+// EndpointsFunc adapts a function to an EndpointSource.
+type EndpointsFunc func() ([]string, error)
+
+// Endpoints calls the function.
+func (f EndpointsFunc) Endpoints() ([]string, error) {
+	return f()
+}
+
+// SRVEndpoints looks up base urls from DNS SRV records,
+// e.g. _http._tcp.stock-service.default.svc.cluster.local
+type SRVEndpoints struct {
+	Service string
+	Proto   string
+	Name    string
+	Scheme  string
+}
+
+// Endpoints resolves the SRV records into base urls.
+func (s SRVEndpoints) Endpoints() ([]string, error) {
+	_, records, err := net.LookupSRV(s.Service, s.Proto, s.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := s.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	endpoints := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		endpoints = append(endpoints, fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(int(record.Port)))))
+	}
+
+	return endpoints, nil
+}
+
+// BalancerConfig configures a load balancer.
+type BalancerConfig struct {
+	Strategy Strategy
+	Source   EndpointSource
+	// RefreshInterval is how often the endpoints are fetched from the source. Zero disables refreshing.
+	RefreshInterval time.Duration
+	// EjectAfter is the number of consecutive failures after which an endpoint is ejected. Zero disables ejection.
+	EjectAfter int
+	// EjectFor is the time an ejected endpoint is left out of the rotation.
+	EjectFor time.Duration
+}
+
+type endpoint struct {
+	baseURL      string
+	inFlight     int
+	failures     int
+	ejectedUntil time.Time
+}
+
+// Balancer spreads requests over the replicas of a downstream service and
+// ejects replicas that keep failing. A balancer may be shared between clients.
+type Balancer struct {
+	cfg BalancerConfig
+
+	mu          sync.Mutex
+	endpoints   []*endpoint
+	next        int
+	refreshedAt time.Time
+	refreshing  bool
+	now         func() time.Time
+}
+
+// NewBalancer creates a balancer and fetches the initial endpoints from the source.
+func NewBalancer(cfg BalancerConfig) (*Balancer, error) {
+	b := &Balancer{
+		cfg: cfg,
+		now: time.Now,
+	}
+
+	err := b.Refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Refresh fetches the endpoints from the source. The state of endpoints
+// that are still present is kept.
+func (b *Balancer) Refresh() error {
+	baseURLs, err := b.cfg.Source.Endpoints()
+	if err != nil {
+		return err
+	}
+	if len(baseURLs) == 0 {
+		return ErrNoEndpoints
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := make(map[string]*endpoint, len(b.endpoints))
+	for _, e := range b.endpoints {
+		current[e.baseURL] = e
+	}
+
+	endpoints := make([]*endpoint, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		baseURL = strings.TrimSuffix(baseURL, "/")
+		if e, ok := current[baseURL]; ok {
+			endpoints = append(endpoints, e)
+			continue
+		}
+		endpoints = append(endpoints, &endpoint{baseURL: baseURL})
+	}
+
+	b.endpoints = endpoints
+	b.refreshedAt = b.now()
+	return nil
+}
+
+// Endpoints returns the base urls currently used by the balancer.
+func (b *Balancer) Endpoints() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	baseURLs := make([]string, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		baseURLs = append(baseURLs, e.baseURL)
+	}
+
+	return baseURLs
+}
+
+// pick selects the endpoint for a request. If every endpoint is ejected
+// all of them are considered, since failing requests beats sending none.
+func (b *Balancer) pick() *endpoint {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refreshIfStale()
+	now := b.now()
+	candidates := make([]*endpoint, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		if now.After(e.ejectedUntil) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = b.endpoints
+	}
+
+	var selected *endpoint
+	switch b.cfg.Strategy {
+	case LeastInFlight:
+		for _, e := range candidates {
+			if selected == nil || e.inFlight < selected.inFlight {
+				selected = e
+			}
+		}
+	default:
+		selected = candidates[b.next%len(candidates)]
+		b.next++
+	}
+
+	selected.inFlight++
+	return selected
+}
+
+// done registers the outcome of a request sent to an endpoint.
+func (b *Balancer) done(e *endpoint, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e.inFlight--
+	if !failed {
+		e.failures = 0
+		return
+	}
+
+	e.failures++
+	if b.cfg.EjectAfter > 0 && e.failures >= b.cfg.EjectAfter {
+		log.Warnw("Ejecting failing endpoint", "endpoint", e.baseURL, "failures", e.failures, "ejectFor", b.cfg.EjectFor)
+		e.ejectedUntil = b.now().Add(b.cfg.EjectFor)
+		e.failures = 0
+	}
+}
+
+// refreshIfStale refreshes the endpoints in the background once the refresh interval has passed.
+// Must be called with the lock held.
+func (b *Balancer) refreshIfStale() {
+	if b.cfg.RefreshInterval <= 0 || b.refreshing || b.now().Sub(b.refreshedAt) < b.cfg.RefreshInterval {
+		return
+	}
+
+	b.refreshing = true
+	go func() {
+		err := b.Refresh()
+		if err != nil {
+			log.Warnw("Failed to refresh endpoints", "error", err)
+		}
+
+		b.mu.Lock()
+		b.refreshing = false
+		b.refreshedAt = b.now()
+		b.mu.Unlock()
+	}()
+}
+
+// withBaseURL creates a copy of a request sent to another base url.
+func withBaseURL(req *http.Request, baseURL, path string) (*http.Request, error) {
+	u, err := url.Parse(baseURL + path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.WithContext(req.Context())
+	r.URL = u
+	r.Host = u.Host
+	return r, nil
+}
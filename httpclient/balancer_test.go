@@ -0,0 +1,99 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func countingServer(calls *int32, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+	}))
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	assert := assert.New(t)
+
+	var first, second int32
+	server1 := countingServer(&first, http.StatusOK)
+	defer server1.Close()
+	server2 := countingServer(&second, http.StatusOK)
+	defer server2.Close()
+
+	balancer, err := NewBalancer(BalancerConfig{
+		Strategy: RoundRobin,
+		Source:   StaticEndpoints{server1.URL, server2.URL + "/"},
+	})
+	assert.NoError(err)
+
+	c := NewClient("balancer-test", "http://stock-service", WithBalancer(balancer))
+	ctx := context.NewBackground("client-id", "sv", "")
+	for i := 0; i < 4; i++ {
+		res, err := c.Get(ctx, "/v1/stocks")
+		assert.NoError(err)
+		res.Body.Close()
+	}
+
+	assert.Equal(int32(2), atomic.LoadInt32(&first))
+	assert.Equal(int32(2), atomic.LoadInt32(&second))
+}
+
+func TestBalancerEjection(t *testing.T) {
+	assert := assert.New(t)
+
+	var healthy, failing int32
+	server1 := countingServer(&healthy, http.StatusOK)
+	defer server1.Close()
+	server2 := countingServer(&failing, http.StatusServiceUnavailable)
+	defer server2.Close()
+
+	balancer, err := NewBalancer(BalancerConfig{
+		Source:     StaticEndpoints{server2.URL, server1.URL},
+		EjectAfter: 1,
+		EjectFor:   time.Minute,
+	})
+	assert.NoError(err)
+
+	c := NewClient("balancer-test", "http://stock-service", WithBalancer(balancer), WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+	for i := 0; i < 4; i++ {
+		res, err := c.Get(ctx, "/v1/stocks")
+		assert.NoError(err)
+		res.Body.Close()
+	}
+
+	assert.Equal(int32(1), atomic.LoadInt32(&failing))
+	assert.Equal(int32(4), atomic.LoadInt32(&healthy))
+}
+
+func TestBalancerLeastInFlightAndRefresh(t *testing.T) {
+	assert := assert.New(t)
+
+	endpoints := []string{"http://replica-1", "http://replica-2"}
+	balancer, err := NewBalancer(BalancerConfig{
+		Strategy: LeastInFlight,
+		Source:   EndpointsFunc(func() ([]string, error) { return endpoints, nil }),
+	})
+	assert.NoError(err)
+
+	first := balancer.pick()
+	second := balancer.pick()
+	assert.NotEqual(first.baseURL, second.baseURL)
+
+	balancer.done(first, false)
+	assert.Equal(first.baseURL, balancer.pick().baseURL)
+
+	endpoints = []string{"http://replica-2", "http://replica-3"}
+	assert.NoError(balancer.Refresh())
+	assert.Equal(endpoints, balancer.Endpoints())
+
+	endpoints = nil
+	assert.Equal(ErrNoEndpoints, balancer.Refresh())
+}
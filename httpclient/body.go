@@ -0,0 +1,165 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+// Body is a request body with an explicit content type.
+// Bodies of any other type are encoded as JSON.
+type Body interface {
+	ContentType() string
+	Reader() (io.Reader, error)
+}
+
+type streamBody struct {
+	reader      io.Reader
+	contentType string
+}
+
+// Stream creates a body that is streamed from the reader without being buffered in memory.
+// Unless the reader is a bytes.Buffer, bytes.Reader or strings.Reader the body
+// cannot be rewound, and requests sending it are not retried.
+func Stream(reader io.Reader, contentType string) Body {
+	return streamBody{
+		reader:      reader,
+		contentType: contentType,
+	}
+}
+
+func (b streamBody) ContentType() string {
+	return b.contentType
+}
+
+func (b streamBody) Reader() (io.Reader, error) {
+	return b.reader, nil
+}
+
+type jsonBody struct {
+	value interface{}
+}
+
+// JSON creates a body encoded as JSON, the same as passing the value itself.
+func JSON(value interface{}) Body {
+	return jsonBody{value: value}
+}
+
+func (b jsonBody) ContentType() string {
+	return "application/json"
+}
+
+func (b jsonBody) Reader() (io.Reader, error) {
+	content, err := json.Marshal(b.value)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(content), nil
+}
+
+type formBody struct {
+	values url.Values
+}
+
+// Form creates a url encoded form body.
+func Form(values url.Values) Body {
+	return formBody{values: values}
+}
+
+func (b formBody) ContentType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+func (b formBody) Reader() (io.Reader, error) {
+	return strings.NewReader(b.values.Encode()), nil
+}
+
+type rawBody struct {
+	content     []byte
+	contentType string
+}
+
+// Raw creates a body sending the content as is.
+func Raw(content []byte, contentType string) Body {
+	return rawBody{
+		content:     content,
+		contentType: contentType,
+	}
+}
+
+func (b rawBody) ContentType() string {
+	return b.contentType
+}
+
+func (b rawBody) Reader() (io.Reader, error) {
+	return bytes.NewReader(b.content), nil
+}
+
+// File is a file uploaded in a multipart body.
+type File struct {
+	Field    string
+	Filename string
+	Content  io.Reader
+}
+
+type multipartBody struct {
+	fields   url.Values
+	files    []File
+	boundary string
+}
+
+// Multipart creates a multipart/form-data body with form fields and files.
+// The body is encoded in memory, so that requests sending it can be retried.
+func Multipart(fields url.Values, files ...File) Body {
+	return multipartBody{
+		fields:   fields,
+		files:    files,
+		boundary: multipart.NewWriter(ioutil.Discard).Boundary(),
+	}
+}
+
+func (b multipartBody) ContentType() string {
+	return "multipart/form-data; boundary=" + b.boundary
+}
+
+func (b multipartBody) Reader() (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	writer := multipart.NewWriter(buf)
+	err := writer.SetBoundary(b.boundary)
+	if err != nil {
+		return nil, err
+	}
+
+	for field, values := range b.fields {
+		for _, value := range values {
+			err = writer.WriteField(field, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, file := range b.files {
+		part, err := writer.CreateFormFile(file.Field, file.Filename)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.Copy(part, file.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(buf.Bytes()), nil
+}
@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormBody(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.NoError(r.ParseForm())
+		assert.Equal("AAPL", r.PostForm.Get("symbol"))
+		assert.Equal([]string{"tech", "usa"}, r.PostForm["tag"])
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClient("body-test", server.URL)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	form := url.Values{"symbol": {"AAPL"}, "tag": {"tech", "usa"}}
+	res, err := c.Post(ctx, "/v1/sources", Form(form))
+	assert.NoError(err)
+	assert.Equal(http.StatusNoContent, res.StatusCode)
+}
+
+func TestMultipartBody(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data; boundary="))
+		assert.NoError(r.ParseMultipartForm(1 << 20))
+		assert.Equal("reuters", r.FormValue("source"))
+
+		file, header, err := r.FormFile("feed")
+		assert.NoError(err)
+		assert.Equal("feed.xml", header.Filename)
+		content, _ := ioutil.ReadAll(file)
+		assert.Equal("<rss></rss>", string(content))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClient("body-test", server.URL)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	body := Multipart(url.Values{"source": {"reuters"}}, File{
+		Field:    "feed",
+		Filename: "feed.xml",
+		Content:  strings.NewReader("<rss></rss>"),
+	})
+	_, err := c.Post(ctx, "/v1/feeds", body)
+	assert.NoError(err)
+}
+
+func TestRawBodyAndAccept(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		assert.Equal("text/plain", r.Header.Get("Content-Type"))
+		assert.Equal("AAPL", string(content))
+		w.Header().Set("X-Accept", r.Header.Get("Accept"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClient("body-test", server.URL)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Put(ctx, "/v1/symbols", Raw([]byte("AAPL"), "text/plain"))
+	assert.NoError(err)
+	assert.Equal("application/json", res.Header.Get("X-Accept"))
+
+	res, err = c.Put(WithCallOptions(ctx, Accept("text/csv")), "/v1/symbols", Raw([]byte("AAPL"), "text/plain"))
+	assert.NoError(err)
+	assert.Equal("text/csv", res.Header.Get("X-Accept"))
+}
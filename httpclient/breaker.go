@@ -0,0 +1,243 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// BreakerState state of a circuit breaker.
+type BreakerState int
+
+// Circuit breaker states.
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures when a circuit breaker trips and recovers.
+type BreakerConfig struct {
+	// ConsecutiveFailures trips the breaker after this many failures in a row. Zero disables the check.
+	ConsecutiveFailures int
+	// FailureRate trips the breaker when the share of failed requests (0-1) in
+	// the current window reaches it. Zero disables the check.
+	FailureRate float64
+	// MinRequests is the number of requests needed in a window before the failure rate is considered.
+	MinRequests int
+	// Window is the interval after which the failure counts of a closed breaker are reset.
+	Window time.Duration
+	// CoolDown is the time an open breaker waits before letting probe requests through.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of successful probes needed to close the breaker again.
+	HalfOpenRequests int
+}
+
+// DefaultBreakerConfig default circuit breaker configuration.
+var DefaultBreakerConfig = BreakerConfig{
+	ConsecutiveFailures: 5,
+	FailureRate:         0.5,
+	MinRequests:         20,
+	Window:              time.Minute,
+	CoolDown:            30 * time.Second,
+	HalfOpenRequests:    1,
+}
+
+// Circuit breakers are shared by all clients with the same name.
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*circuitBreaker)
+)
+
+// getBreaker returns the circuit breaker for a client name, creating it
+// with the supplied config if it does not exist yet.
+func getBreaker(name string, cfg BreakerConfig) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[name]
+	if !ok {
+		b = newCircuitBreaker(name, cfg)
+		breakers[name] = b
+		return b
+	}
+
+	if b.cfg != cfg.withDefaults() {
+		log.Warnw("Circuit breaker already exists with another config, keeping the existing config",
+			"client", name,
+			"config", fmt.Sprintf("%+v", b.cfg),
+			"ignoredConfig", fmt.Sprintf("%+v", cfg))
+	}
+
+	return b
+}
+
+type circuitBreaker struct {
+	name string
+	cfg  BreakerConfig
+
+	mu          sync.Mutex
+	state       BreakerState
+	requests    int
+	failures    int
+	consecutive int
+	probes      int
+	successes   int
+	windowStart time.Time
+	openedAt    time.Time
+	now         func() time.Time
+}
+
+// withDefaults returns the config with a probe count of at least one.
+func (cfg BreakerConfig) withDefaults() BreakerConfig {
+	if cfg.HalfOpenRequests < 1 {
+		cfg.HalfOpenRequests = 1
+	}
+	return cfg
+}
+
+func newCircuitBreaker(name string, cfg BreakerConfig) *circuitBreaker {
+	b := &circuitBreaker{
+		name:        name,
+		cfg:         cfg.withDefaults(),
+		state:       BreakerClosed,
+		windowStart: time.Now(),
+		now:         time.Now,
+	}
+	b.publishState()
+	return b
+}
+
+// allow checks if a request may be sent. Returns an error if the breaker is open.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cfg.CoolDown {
+			return b.openError()
+		}
+		b.setState(BreakerHalfOpen, now)
+	case BreakerClosed:
+		if b.cfg.Window > 0 && now.Sub(b.windowStart) >= b.cfg.Window {
+			b.resetCounts(now)
+		}
+	}
+
+	if b.state == BreakerHalfOpen {
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return b.openError()
+		}
+		b.probes++
+	}
+
+	return nil
+}
+
+// record registers the outcome of a request allowed by the breaker.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.state == BreakerHalfOpen {
+		if b.probes > 0 {
+			b.probes--
+		}
+		if failed {
+			b.setState(BreakerOpen, now)
+			return
+		}
+
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(BreakerClosed, now)
+		}
+		return
+	}
+
+	if b.state != BreakerClosed {
+		return
+	}
+
+	b.requests++
+	if !failed {
+		b.consecutive = 0
+		return
+	}
+
+	b.failures++
+	b.consecutive++
+	if b.shouldTrip() {
+		log.Warnw("Circuit breaker opened", "client", b.name, "failures", b.failures, "requests", b.requests, "consecutiveFailures", b.consecutive)
+		b.setState(BreakerOpen, now)
+	}
+}
+
+func (b *circuitBreaker) shouldTrip() bool {
+	if b.cfg.ConsecutiveFailures > 0 && b.consecutive >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+
+	if b.cfg.FailureRate <= 0 || b.requests < b.cfg.MinRequests {
+		return false
+	}
+
+	return float64(b.failures)/float64(b.requests) >= b.cfg.FailureRate
+}
+
+func (b *circuitBreaker) setState(state BreakerState, now time.Time) {
+	b.state = state
+	b.probes = 0
+	b.successes = 0
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+	b.resetCounts(now)
+	b.publishState()
+}
+
+func (b *circuitBreaker) resetCounts(now time.Time) {
+	b.requests = 0
+	b.failures = 0
+	b.consecutive = 0
+	b.windowStart = now
+}
+
+func (b *circuitBreaker) publishState() {
+	rpcBreakerState.WithLabelValues(b.name).Set(float64(b.state))
+}
+
+func (b *circuitBreaker) openError() error {
+	message := fmt.Sprintf("Circuit breaker for downstream service %s is %s", b.name, b.state)
+	return httputil.ServiceUnavailable(message)
+}
+
+// breakerFailure checks if the outcome of a call counts as a failure for the circuit breaker.
+// Calls cancelled by the caller or cut short by a per call timeout say nothing about the health
+// of the downstream service and are not counted, while calls exceeding the client timeout are.
+func breakerFailure(call *Call, res *http.Response, err error) bool {
+	if err != nil && call.Request.Context().Err() != nil {
+		return call.Ctx.Err() == nil && !getCallOptions(call.Ctx).hasTimeout
+	}
+
+	return err != nil || res.StatusCode >= 500
+}
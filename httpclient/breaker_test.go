@@ -0,0 +1,172 @@
+package httpclient
+
+import (
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/mimir-news/mimir-go/id"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	b := newCircuitBreaker("breaker-states-test", BreakerConfig{
+		ConsecutiveFailures: 3,
+		CoolDown:            10 * time.Second,
+		HalfOpenRequests:    1,
+	})
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(b.allow())
+		b.record(true)
+	}
+	assert.Equal(BreakerOpen, b.state)
+
+	err := b.allow()
+	assert.Error(err)
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusServiceUnavailable, httpErr.StatusCode)
+
+	now = now.Add(11 * time.Second)
+	assert.NoError(b.allow())
+	assert.Equal(BreakerHalfOpen, b.state)
+	assert.Error(b.allow(), "Only one probe should be let through while half-open")
+
+	b.record(true)
+	assert.Equal(BreakerOpen, b.state)
+
+	now = now.Add(11 * time.Second)
+	assert.NoError(b.allow())
+	b.record(false)
+	assert.Equal(BreakerClosed, b.state)
+	assert.NoError(b.allow())
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	assert := assert.New(t)
+
+	b := newCircuitBreaker("breaker-rate-test", BreakerConfig{
+		FailureRate: 0.5,
+		MinRequests: 4,
+		Window:      time.Minute,
+		CoolDown:    time.Minute,
+	})
+
+	b.record(true)
+	b.record(false)
+	b.record(false)
+	assert.Equal(BreakerClosed, b.state)
+
+	b.record(true)
+	assert.Equal(BreakerOpen, b.state)
+}
+
+func TestClientWithCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := BreakerConfig{ConsecutiveFailures: 2, CoolDown: time.Minute}
+	// Breakers are shared by name, so a unique name keeps repeated test runs independent.
+	c := New("breaker-client-test-"+id.New(), server.URL, time.Second, WithRetryPolicy(NoRetries), WithCircuitBreaker(cfg))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(ctx, "/v1/things")
+		httpErr, ok := err.(*httputil.Error)
+		assert.True(ok)
+		assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+	}
+
+	_, err := c.Get(ctx, "/v1/things")
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusServiceUnavailable, httpErr.StatusCode)
+	assert.Equal(int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestGetBreakerKeepsExistingConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	name := "breaker-config-test-" + id.New()
+	first := getBreaker(name, BreakerConfig{ConsecutiveFailures: 2})
+	second := getBreaker(name, BreakerConfig{ConsecutiveFailures: 10})
+
+	assert.True(first == second)
+	assert.Equal(2, second.cfg.ConsecutiveFailures)
+	assert.Equal(1, second.cfg.HalfOpenRequests)
+}
+
+func TestBreakerProbeReturnedOnMalformedEndpoint(t *testing.T) {
+	assert := assert.New(t)
+
+	balancer, err := NewBalancer(BalancerConfig{Source: StaticEndpoints{"http://stock service"}})
+	assert.NoError(err)
+
+	cfg := BreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Minute}
+	c := NewClient("breaker-probe-test-"+id.New(), "http://stock-service", WithRetryPolicy(NoRetries), WithCircuitBreaker(cfg), WithBalancer(balancer))
+	b := c.(*client).breaker
+	b.mu.Lock()
+	b.setState(BreakerHalfOpen, time.Now())
+	b.mu.Unlock()
+
+	_, err = c.Get(context.NewBackground("client-id", "sv", ""), "/v1/stocks")
+	assert.Error(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	assert.Equal(0, b.probes)
+	assert.Equal(BreakerOpen, b.state)
+}
+
+func TestBreakerIgnoresCallerCancellation(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := BreakerConfig{ConsecutiveFailures: 2, CoolDown: time.Minute}
+	name := "breaker-cancel-test-" + id.New()
+	c := NewClient(name, server.URL, WithTimeout(20*time.Millisecond), WithRetryPolicy(NoRetries), WithCircuitBreaker(cfg))
+	state := rpcBreakerState.WithLabelValues(name)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(WithCallOptions(ctx, Timeout(5*time.Millisecond)), "/v1/things")
+		assert.Error(err)
+
+		cancelCtx, cancel := stdcontext.WithCancel(stdcontext.Background())
+		time.AfterFunc(5*time.Millisecond, cancel)
+		_, err = c.Get(context.New(cancelCtx, "request-id", "client-id", "sv", ""), "/v1/things")
+		assert.Error(err)
+	}
+	assert.Equal(float64(BreakerClosed), testutil.ToFloat64(state))
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(ctx, "/v1/things")
+		assert.Error(err)
+	}
+	assert.Equal(float64(BreakerOpen), testutil.ToFloat64(state), "client timeouts should count as failures")
+}
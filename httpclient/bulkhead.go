@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// BulkheadConfig caps the concurrent requests of a client, so that a slow
+// downstream service cannot use up every goroutine and connection.
+type BulkheadConfig struct {
+	// MaxConcurrent is the maximum number of requests in flight.
+	MaxConcurrent int
+	// MaxQueued is the maximum number of calls waiting for a request to finish. Zero rejects calls immediately.
+	MaxQueued int
+	// QueueTimeout is the maximum time a call waits in the queue. Zero waits until the call context is done.
+	QueueTimeout time.Duration
+}
+
+type bulkhead struct {
+	client string
+	cfg    BulkheadConfig
+	slots  chan struct{}
+
+	mu     sync.Mutex
+	queued int
+}
+
+func newBulkhead(client string, cfg BulkheadConfig) *bulkhead {
+	return &bulkhead{
+		client: client,
+		cfg:    cfg,
+		slots:  make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// intercept holds a slot for a call, including its retries, until the response body is closed.
+func (b *bulkhead) intercept(call *Call, next Invoker) (*http.Response, error) {
+	err := b.acquire(call)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := next(call)
+	if res == nil || res.Body == nil {
+		b.release()
+		return res, err
+	}
+
+	res.Body = &releaseOnClose{ReadCloser: res.Body, release: b.release}
+	return res, err
+}
+
+func (b *bulkhead) acquire(call *Call) error {
+	select {
+	case b.slots <- struct{}{}:
+		rpcBulkheadInFlight.WithLabelValues(b.client).Inc()
+		return nil
+	default:
+	}
+
+	b.mu.Lock()
+	if b.queued >= b.cfg.MaxQueued {
+		b.mu.Unlock()
+		return b.rejectedError(call, "queue full")
+	}
+	b.queued++
+	rpcBulkheadQueued.WithLabelValues(b.client).Inc()
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.queued--
+		rpcBulkheadQueued.WithLabelValues(b.client).Dec()
+		b.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if b.cfg.QueueTimeout > 0 {
+		timer := time.NewTimer(b.cfg.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	ctx := call.Request.Context()
+	select {
+	case b.slots <- struct{}{}:
+		rpcBulkheadInFlight.WithLabelValues(b.client).Inc()
+		return nil
+	case <-timeout:
+		return b.rejectedError(call, "queue timeout")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bulkhead) release() {
+	<-b.slots
+	rpcBulkheadInFlight.WithLabelValues(b.client).Dec()
+}
+
+func (b *bulkhead) rejectedError(call *Call, reason string) error {
+	message := fmt.Sprintf("Too many concurrent requests to downstream service %s, %s. requestId=[%s] path=[%s]", b.client, reason, call.Ctx.ID, stripQueryParameters(call.Path))
+	return httputil.ServiceUnavailable(message)
+}
+
+// releaseOnClose frees the bulkhead slot of a call once the response body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
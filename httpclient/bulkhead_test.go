@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkhead(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClient("bulkhead-test", server.URL, WithBulkhead(BulkheadConfig{
+		MaxConcurrent: 1,
+		MaxQueued:     1,
+		QueueTimeout:  50 * time.Millisecond,
+	}))
+	ctx := context.NewBackground("client-id", "sv", "")
+	inFlight := rpcBulkheadInFlight.WithLabelValues("bulkhead-test")
+	queued := rpcBulkheadQueued.WithLabelValues("bulkhead-test")
+
+	first := make(chan error)
+	go func() {
+		res, err := c.Get(ctx, "/v1/stocks")
+		if err == nil {
+			res.Body.Close()
+		}
+		first <- err
+	}()
+	waitForGauge(t, inFlight, 1)
+
+	queuedErr := make(chan error)
+	go func() {
+		_, err := c.Get(ctx, "/v1/stocks")
+		queuedErr <- err
+	}()
+	waitForGauge(t, queued, 1)
+
+	_, err := c.Get(ctx, "/v1/stocks")
+	assertUnavailable(assert, err, "queue full")
+
+	err = <-queuedErr
+	assertUnavailable(assert, err, "queue timeout")
+	assert.Equal(float64(0), testutil.ToFloat64(queued))
+
+	close(release)
+	assert.NoError(<-first)
+	assert.Equal(float64(0), testutil.ToFloat64(inFlight))
+
+	res, err := c.Get(ctx, "/v1/stocks")
+	assert.NoError(err)
+	res.Body.Close()
+}
+
+func assertUnavailable(assert *assert.Assertions, err error, reason string) {
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	if ok {
+		assert.Equal(http.StatusServiceUnavailable, httpErr.StatusCode)
+		assert.True(strings.Contains(httpErr.Message, reason), httpErr.Message)
+	}
+}
+
+func waitForGauge(t *testing.T, gauge prometheus.Gauge, value float64) {
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(gauge) != value {
+		if time.Now().After(deadline) {
+			t.Fatalf("Gauge did not reach %v", value)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
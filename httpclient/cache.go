@@ -0,0 +1,271 @@
+package httpclient
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache results.
+const (
+	cacheHit         = "hit"
+	cacheMiss        = "miss"
+	cacheRevalidated = "revalidated"
+)
+
+// CachedResponse is a downstream response kept in a CacheStore.
+type CachedResponse struct {
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified string
+	Expires      time.Time
+}
+
+// CacheStore stores cached responses by key.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, res *CachedResponse)
+	Delete(key string)
+}
+
+type responseCache struct {
+	client string
+	store  CacheStore
+	now    func() time.Time
+}
+
+func newResponseCache(client string, store CacheStore) *responseCache {
+	return &responseCache{
+		client: client,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// intercept serves GET requests from the cache while fresh and revalidates
+// stale entries with If-None-Match and If-Modified-Since headers.
+func (rc *responseCache) intercept(call *Call, next Invoker) (*http.Response, error) {
+	req := call.Request
+	if req.Method != http.MethodGet {
+		return next(call)
+	}
+
+	key := cacheKey(call)
+	cached, ok := rc.store.Get(key)
+	if ok && rc.now().Before(cached.Expires) {
+		rc.record(cacheHit)
+		return cached.response(req), nil
+	}
+
+	if ok {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	res, err := next(call)
+	if err != nil {
+		return res, err
+	}
+
+	if ok && res.StatusCode == http.StatusNotModified {
+		rc.record(cacheRevalidated)
+		drainAndClose(res)
+		cached.Expires = rc.expires(res.Header)
+		rc.store.Set(key, cached)
+		return cached.response(req), nil
+	}
+
+	rc.record(cacheMiss)
+	if res.StatusCode != http.StatusOK || !storable(res.Header) {
+		rc.store.Delete(key)
+		return res, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	cached = &CachedResponse{
+		StatusCode:   res.StatusCode,
+		Header:       res.Header,
+		Body:         body,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		Expires:      rc.expires(res.Header),
+	}
+	rc.store.Set(key, cached)
+	return cached.response(req), nil
+}
+
+func (rc *responseCache) expires(header http.Header) time.Time {
+	directives := parseCacheControl(header)
+	if _, ok := directives["no-cache"]; ok {
+		return time.Time{}
+	}
+
+	maxAge, err := strconv.Atoi(directives["max-age"])
+	if err != nil || maxAge <= 0 {
+		return time.Time{}
+	}
+
+	return rc.now().Add(time.Duration(maxAge) * time.Second)
+}
+
+func (rc *responseCache) record(result string) {
+	rpcCacheTotal.WithLabelValues(rc.client, result).Inc()
+}
+
+// response creates a new http response from a cached response.
+func (cr *CachedResponse) response(req *http.Request) *http.Response {
+	header := make(http.Header, len(cr.Header))
+	for key, values := range cr.Header {
+		header[key] = append([]string(nil), values...)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(cr.StatusCode) + " " + http.StatusText(cr.StatusCode),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+}
+
+// storable checks if a response may be kept in a cache shared between users
+// and carries anything that makes caching it worthwhile.
+func storable(header http.Header) bool {
+	directives := parseCacheControl(header)
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	if _, ok := directives["private"]; ok {
+		return false
+	}
+
+	_, hasMaxAge := directives["max-age"]
+	return hasMaxAge || header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+}
+
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header.Get("Cache-Control"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		keyAndValue := strings.SplitN(part, "=", 2)
+		key := strings.ToLower(strings.TrimSpace(keyAndValue[0]))
+		value := ""
+		if len(keyAndValue) == 2 {
+			value = strings.Trim(strings.TrimSpace(keyAndValue[1]), `"`)
+		}
+		directives[key] = value
+	}
+
+	return directives
+}
+
+// cacheKey creates the key of a call based on its url, accepted content type, language and
+// a hash of the auth token, so that responses are never shared between users.
+func cacheKey(call *Call) string {
+	return strings.Join([]string{
+		call.Request.URL.String(),
+		call.Request.Header.Get("Accept"),
+		call.Ctx.Language,
+		tokenHash(call.Ctx.AuthToken),
+	}, "|")
+}
+
+// tokenHash hashes an auth token, so that tokens are not kept in cache keys.
+func tokenHash(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+type lruEntry struct {
+	key string
+	res *CachedResponse
+}
+
+type lruStore struct {
+	mu      sync.Mutex
+	size    int
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+// NewLRUStore creates an in-memory CacheStore holding at most size responses,
+// evicting the least recently used response when full.
+func NewLRUStore(size int) CacheStore {
+	return &lruStore{
+		size:    size,
+		entries: list.New(),
+		index:   make(map[string]*list.Element),
+	}
+}
+
+func (s *lruStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.index[key]
+	if !ok {
+		return nil, false
+	}
+
+	s.entries.MoveToFront(elem)
+	res := *elem.Value.(*lruEntry).res
+	return &res, true
+}
+
+func (s *lruStore) Set(key string, res *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.index[key]; ok {
+		elem.Value.(*lruEntry).res = res
+		s.entries.MoveToFront(elem)
+		return
+	}
+
+	s.index[key] = s.entries.PushFront(&lruEntry{key: key, res: res})
+	for s.entries.Len() > s.size {
+		oldest := s.entries.Back()
+		s.entries.Remove(oldest)
+		delete(s.index, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (s *lruStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.index[key]; ok {
+		s.entries.Remove(elem)
+		delete(s.index, key)
+	}
+}
@@ -0,0 +1,128 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheRevalidation(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"symbol":"AAPL"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("cache-test", server.URL, WithCache(NewLRUStore(10)))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ctx, "/v1/stocks/AAPL")
+		assert.NoError(err)
+		assert.Equal(http.StatusOK, res.StatusCode)
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.NoError(err)
+		assert.Equal(`{"symbol":"AAPL"}`, string(body))
+	}
+
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(int32(2), atomic.LoadInt32(&notModified))
+}
+
+func TestCacheMaxAgeAndNoStore(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/v1/stocks/FRESH" {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		} else {
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("ETag", `"v1"`)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("cache-test", server.URL, WithCache(NewLRUStore(10)))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ctx, "/v1/stocks/FRESH")
+		assert.NoError(err)
+		res.Body.Close()
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ctx, "/v1/stocks/SECRET")
+		assert.NoError(err)
+		res.Body.Close()
+	}
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestCacheIsPerAuthToken(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	c := NewClient("cache-token-test", server.URL, WithCache(NewLRUStore(10)))
+	get := func(token string) string {
+		res, err := c.Get(context.NewBackground("client-id", "sv", token), "/v1/me")
+		assert.NoError(err)
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		assert.NoError(err)
+		return string(body)
+	}
+
+	assert.Equal("Bearer alice-token", get("alice-token"))
+	assert.Equal("Bearer bob-token", get("bob-token"))
+	assert.Equal("Bearer alice-token", get("alice-token"))
+}
+
+func TestLRUStore(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewLRUStore(2)
+	store.Set("a", &CachedResponse{ETag: "a"})
+	store.Set("b", &CachedResponse{ETag: "b"})
+
+	_, ok := store.Get("a")
+	assert.True(ok)
+
+	store.Set("c", &CachedResponse{ETag: "c"})
+	_, ok = store.Get("b")
+	assert.False(ok, "least recently used entry should be evicted")
+
+	res, ok := store.Get("a")
+	assert.True(ok)
+	assert.Equal("a", res.ETag)
+
+	store.Delete("a")
+	_, ok = store.Get("a")
+	assert.False(ok)
+}
@@ -0,0 +1,159 @@
+package httpclient
+
+import (
+	stdcontext "context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+)
+
+type callOptionsKey struct{}
+
+// CallOption configures a single call made by a client.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout        time.Duration
+	hasTimeout     bool
+	stream         bool
+	accept         string
+	pathParams     map[string]string
+	idempotencyKey string
+}
+
+// WithCallOptions returns a copy of ctx which applies the supplied
+// options to the calls made with it.
+func WithCallOptions(ctx *context.Context, opts ...CallOption) *context.Context {
+	options := getCallOptions(ctx)
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &context.Context{
+		ID:        ctx.ID,
+		ClientID:  ctx.ClientID,
+		Language:  ctx.Language,
+		AuthToken: ctx.AuthToken,
+		Context:   stdcontext.WithValue(ctx.Context, callOptionsKey{}, options),
+	}
+}
+
+// Timeout overrides the client timeout for a call. A timeout of zero or less disables it.
+func Timeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+		o.hasTimeout = true
+	}
+}
+
+// StreamResponse limits the timeout of a call to receiving the response headers,
+// so that a streamed response body can be read for as long as it takes.
+func StreamResponse() CallOption {
+	return func(o *callOptions) {
+		o.stream = true
+	}
+}
+
+// Accept overrides the accepted content type of a call, which defaults to application/json.
+func Accept(contentType string) CallOption {
+	return func(o *callOptions) {
+		o.accept = contentType
+	}
+}
+
+// PathParams expands the :name segments of the path of a call, which is treated as a
+// route template, e.g. /v1/stocks/:symbol/tweets. Values are path escaped and the
+// template rather than the expanded path labels the metrics of the call.
+func PathParams(params map[string]string) CallOption {
+	return func(o *callOptions) {
+		o.pathParams = params
+	}
+}
+
+// IdempotencyKey sets the Idempotency-Key header of a call, which makes it safe to retry.
+// The key is sent with every attempt of the call.
+func IdempotencyKey(key string) CallOption {
+	return func(o *callOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+func getCallOptions(ctx stdcontext.Context) callOptions {
+	options, _ := ctx.Value(callOptionsKey{}).(callOptions)
+	return options
+}
+
+// applyTimeout binds the call or client timeout to a request. The cancel function of the
+// returned timeout must be called once the response body has been consumed.
+func (c *client) applyTimeout(req *http.Request) (*http.Request, *callTimeout) {
+	options := getCallOptions(req.Context())
+	timeout := c.timeout
+	if options.hasTimeout {
+		timeout = options.timeout
+	}
+
+	if timeout <= 0 {
+		return req, &callTimeout{cancel: func() {}}
+	}
+
+	if !options.stream {
+		ctx, cancel := stdcontext.WithTimeout(req.Context(), timeout)
+		return req.WithContext(ctx), &callTimeout{cancel: cancel}
+	}
+
+	ctx, cancel := stdcontext.WithCancel(req.Context())
+	t := &callTimeout{cancel: cancel}
+	t.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&t.expired, 1)
+		cancel()
+	})
+	return req.WithContext(ctx), t
+}
+
+// callTimeout is the timeout of a call. The timeout of a streamed call is a
+// timer that is stopped once the response headers have been received.
+type callTimeout struct {
+	cancel  stdcontext.CancelFunc
+	timer   *time.Timer
+	expired int32
+}
+
+// headersReceived stops the timeout of a streamed call.
+func (t *callTimeout) headersReceived() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// wrap replaces the error of a streamed call cancelled by its timer with context.DeadlineExceeded.
+func (t *callTimeout) wrap(err error) error {
+	if err != nil && atomic.LoadInt32(&t.expired) == 1 {
+		return stdcontext.DeadlineExceeded
+	}
+	return err
+}
+
+// cancelOnClose releases the request context when the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel stdcontext.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// isTimeout checks if an error was caused by a deadline being exceeded.
+func isTimeout(err error) bool {
+	if err == stdcontext.DeadlineExceeded {
+		return true
+	}
+
+	timeoutErr, ok := err.(interface{ Timeout() bool })
+	return ok && timeoutErr.Timeout()
+}
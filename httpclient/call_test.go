@@ -0,0 +1,83 @@
+package httpclient
+
+import (
+	stdcontext "context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	defer server.Close()
+
+	c := New("timeout-test", server.URL, time.Second, WithTimeout(20*time.Millisecond), WithRetryPolicy(NoRetries))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	_, err := c.Get(ctx, "/v1/things")
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusGatewayTimeout, httpErr.StatusCode)
+
+	res, err := c.Get(WithCallOptions(ctx, Timeout(time.Second)), "/v1/things")
+	assert.NoError(err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(err)
+	assert.Equal(`{"status":"OK"}`, string(body))
+
+	res, err = c.Get(WithCallOptions(ctx, Timeout(0)), "/v1/things")
+	assert.NoError(err, "a zero call timeout should disable the client timeout")
+	res.Body.Close()
+}
+
+func TestClientCancelledContext(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := New("cancel-test", server.URL, time.Second, WithRetryPolicy(testRetryPolicy))
+	parent, cancel := stdcontext.WithCancel(stdcontext.Background())
+	ctx := context.New(parent, "request-id", "client-id", "sv", "")
+	cancel()
+
+	_, err := c.Get(ctx, "/v1/things")
+	assert.Error(err)
+	assert.Equal(int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestWithCallOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.NewBackground("client-id", "sv", "auth-token")
+	callCtx := WithCallOptions(ctx, Timeout(time.Second))
+
+	assert.Equal(ctx.ID, callCtx.ID)
+	assert.Equal(ctx.ClientID, callCtx.ClientID)
+	assert.Equal(ctx.Language, callCtx.Language)
+	assert.Equal(ctx.AuthToken, callCtx.AuthToken)
+	assert.Equal(ctx.ID, callCtx.Value(context.ContextIDKey))
+	assert.Equal(time.Second, getCallOptions(callCtx).timeout)
+	assert.Equal(time.Duration(0), getCallOptions(ctx).timeout)
+}
@@ -0,0 +1,429 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mimir-news/mimir-go/environ"
+)
+
+// Environment variables enabling cassettes for all clients.
+const (
+	CassetteModeEnv   = "HTTPCLIENT_CASSETTE_MODE"
+	CassetteDirEnv    = "HTTPCLIENT_CASSETTE_DIR"
+	CassetteRedactEnv = "HTTPCLIENT_CASSETTE_REDACT"
+	CassetteMatchEnv  = "HTTPCLIENT_CASSETTE_MATCH"
+)
+
+const redactedValue = "REDACTED"
+
+// CassetteMode decides if a cassette records or replays interactions.
+type CassetteMode string
+
+// Cassette modes.
+const (
+	CassetteRecord CassetteMode = "record"
+	CassetteReplay CassetteMode = "replay"
+)
+
+// MatchRules decides which parts of a request must be equal to a recorded request for it to be replayed.
+type MatchRules struct {
+	Method bool
+	Path   bool
+	Query  bool
+	Body   bool
+}
+
+// DefaultMatchRules matches requests on method, path and query.
+var DefaultMatchRules = MatchRules{Method: true, Path: true, Query: true}
+
+// CassetteConfig configures a cassette.
+type CassetteConfig struct {
+	Mode CassetteMode
+	// Path is the cassette file.
+	Path string
+	// Redact are headers, in addition to Authorization, whose values are not recorded.
+	Redact []string
+	// Match defaults to DefaultMatchRules if not set.
+	Match MatchRules
+}
+
+// Interaction is a recorded request and response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request stored in a cassette.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response stored in a cassette.
+type RecordedResponse struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Cassette is a http.RoundTripper that records interactions with downstream
+// services to a JSON file, or replays previously recorded interactions.
+type Cassette struct {
+	cfg       CassetteConfig
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// NewCassette creates a cassette. In replay mode the cassette file is loaded,
+// in record mode requests are sent using the transport and written to the file.
+func NewCassette(cfg CassetteConfig, transport http.RoundTripper) (*Cassette, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if cfg.Match == (MatchRules{}) {
+		cfg.Match = DefaultMatchRules
+	}
+
+	c := &Cassette{
+		cfg:          cfg,
+		transport:    transport,
+		interactions: make([]Interaction, 0),
+	}
+
+	switch cfg.Mode {
+	case CassetteRecord:
+		return c, nil
+	case CassetteReplay:
+		return c, c.load()
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %s", cfg.Mode)
+	}
+}
+
+// RoundTrip records or replays a request.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err = decodeContent(req.Header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cfg.Mode == CassetteReplay {
+		return c.replay(req, body)
+	}
+
+	return c.record(req, body)
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	res, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	decodedResBody, err := decodeContent(res.Header, resBody)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Query:  req.URL.RawQuery,
+			Header: withoutContentEncoding(c.redact(req.Header)),
+			Body:   string(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     withoutContentEncoding(c.redact(res.Header)),
+			Body:       string(decodedResBody),
+		},
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+	return res, c.save()
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	match := -1
+	for i, interaction := range c.interactions {
+		if !c.matches(interaction.Request, req, body) {
+			continue
+		}
+		match = i
+		if !c.replayed[i] {
+			break
+		}
+	}
+
+	if match < 0 {
+		return nil, fmt.Errorf("cassette %s has no interaction matching %s %s", c.cfg.Path, req.Method, req.URL.RequestURI())
+	}
+
+	c.replayed[match] = true
+	recorded := c.interactions[match].Response
+	header := make(http.Header, len(recorded.Header))
+	for key, values := range recorded.Header {
+		header[key] = append([]string(nil), values...)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(recorded.StatusCode) + " " + http.StatusText(recorded.StatusCode),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (c *Cassette) matches(recorded RecordedRequest, req *http.Request, body []byte) bool {
+	rules := c.cfg.Match
+	if rules.Method && recorded.Method != req.Method {
+		return false
+	}
+
+	if rules.Path && recorded.Path != req.URL.Path {
+		return false
+	}
+
+	if rules.Query {
+		recordedQuery, err := url.ParseQuery(recorded.Query)
+		if err != nil || !reflect.DeepEqual(recordedQuery, req.URL.Query()) {
+			return false
+		}
+	}
+
+	return !rules.Body || equalBodies([]byte(recorded.Body), body)
+}
+
+func (c *Cassette) redact(header http.Header) http.Header {
+	return redactHeader(header, append([]string{"Authorization"}, c.cfg.Redact...))
+}
+
+// redactHeader copies a header, replacing the values of the redacted keys.
+func redactHeader(header http.Header, keys []string) http.Header {
+	redacted := make(http.Header, len(header))
+	for key, values := range header {
+		redacted[key] = append([]string(nil), values...)
+	}
+
+	for _, key := range keys {
+		if redacted.Get(key) != "" {
+			redacted.Set(key, redactedValue)
+		}
+	}
+
+	return redacted
+}
+
+func (c *Cassette) load() error {
+	content, err := ioutil.ReadFile(c.cfg.Path)
+	if err != nil {
+		return err
+	}
+
+	var file cassetteFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return err
+	}
+
+	c.interactions = file.Interactions
+	c.replayed = make([]bool, len(file.Interactions))
+	return nil
+}
+
+// save writes the recorded interactions to the cassette file. Must be called with the lock held.
+func (c *Cassette) save() error {
+	content, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.cfg.Path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.cfg.Path, content, 0644)
+}
+
+// Cassettes created from the environment are shared by clients with the same name.
+var (
+	cassettesMu sync.Mutex
+	cassettes   = make(map[string]*Cassette)
+)
+
+// cassetteFromEnv creates a cassette for a client if enabled by the environment, nil otherwise.
+// If the cassette cannot be created, e.g. since the cassette file is missing in replay mode, the
+// returned transport fails every request, so that clients not used by a test do not fail it.
+func cassetteFromEnv(name string, transport http.RoundTripper) http.RoundTripper {
+	mode := environ.Get(CassetteModeEnv, "")
+	if mode == "" {
+		return nil
+	}
+
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+
+	path := filepath.Join(environ.Get(CassetteDirEnv, "testdata/cassettes"), name+".json")
+	if cassette, ok := cassettes[path]; ok {
+		return cassette
+	}
+
+	cfg := CassetteConfig{
+		Mode:   CassetteMode(mode),
+		Path:   path,
+		Redact: splitList(environ.Get(CassetteRedactEnv, "")),
+		Match:  parseMatchRules(environ.Get(CassetteMatchEnv, "")),
+	}
+
+	cassette, err := NewCassette(cfg, transport)
+	if err != nil {
+		log.Errorw("Failed to create cassette", "client", name, "mode", mode, "path", path, "error", err)
+		return failingTransport{err: fmt.Errorf("cassette %s of client %s: %w", path, name, err)}
+	}
+
+	cassettes[path] = cassette
+	return cassette
+}
+
+// failingTransport fails every request with the error that prevented a cassette from being created.
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
+
+// parseMatchRules parses a comma separated list of request parts, e.g. method,path,body.
+func parseMatchRules(value string) MatchRules {
+	if value == "" {
+		return DefaultMatchRules
+	}
+
+	var rules MatchRules
+	for _, part := range splitList(value) {
+		switch strings.ToLower(part) {
+		case "method":
+			rules.Method = true
+		case "path":
+			rules.Path = true
+		case "query":
+			rules.Query = true
+		case "body":
+			rules.Body = true
+		}
+	}
+
+	return rules
+}
+
+func splitList(value string) []string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+// readRequestBody reads the body of a request and replaces it so that it can be sent again.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// decodeContent decompresses a gzip encoded body, so that cassettes store and match
+// bodies as sent by the caller and not as encoded on the wire.
+func decodeContent(header http.Header, body []byte) ([]byte, error) {
+	if len(body) == 0 || !strings.EqualFold(header.Get("Content-Encoding"), gzipEncoding) {
+		return body, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(reader)
+}
+
+// withoutContentEncoding removes the headers describing the encoding of a body
+// from a header, since recorded bodies are stored decoded.
+func withoutContentEncoding(header http.Header) http.Header {
+	if strings.EqualFold(header.Get("Content-Encoding"), gzipEncoding) {
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+	}
+	return header
+}
+
+// equalBodies compares two bodies, as JSON values if both are valid JSON.
+func equalBodies(a, b []byte) bool {
+	var jsonA, jsonB interface{}
+	if json.Unmarshal(a, &jsonA) == nil && json.Unmarshal(b, &jsonB) == nil {
+		return reflect.DeepEqual(jsonA, jsonB)
+	}
+
+	return bytes.Equal(a, b)
+}
@@ -0,0 +1,151 @@
+package httpclient
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "cassettes")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","body":` + string(body) + `}`))
+	}))
+
+	os.Setenv(CassetteDirEnv, dir)
+	os.Setenv(CassetteRedactEnv, "X-Api-Key")
+	os.Setenv(CassetteMatchEnv, "method,path,query,body")
+	defer os.Unsetenv(CassetteDirEnv)
+	defer os.Unsetenv(CassetteRedactEnv)
+	defer os.Unsetenv(CassetteMatchEnv)
+
+	os.Setenv(CassetteModeEnv, string(CassetteRecord))
+	ctx := context.NewBackground("client-id", "sv", "secret-token")
+	c := NewClient("cassette-record-test", server.URL, WithHeader("X-Api-Key", "secret-key"))
+
+	var recorded map[string]interface{}
+	err = PostJSON(c, ctx, "/v1/tweets?lang=sv", map[string]string{"text": "hello"}, &recorded)
+	assert.NoError(err)
+	assert.Equal("/v1/tweets", recorded["path"])
+	server.Close()
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "cassette-record-test.json"))
+	assert.NoError(err)
+	assert.False(strings.Contains(string(content), "secret-token"))
+	assert.False(strings.Contains(string(content), "secret-key"))
+	assert.True(strings.Contains(string(content), redactedValue))
+
+	os.Setenv(CassetteModeEnv, string(CassetteReplay))
+	defer os.Unsetenv(CassetteModeEnv)
+	os.Rename(filepath.Join(dir, "cassette-record-test.json"), filepath.Join(dir, "cassette-replay-test.json"))
+	c = NewClient("cassette-replay-test", server.URL, WithRetryPolicy(NoRetries))
+
+	var replayed map[string]interface{}
+	err = PostJSON(c, ctx, "/v1/tweets?lang=sv", map[string]string{"text": "hello"}, &replayed)
+	assert.NoError(err)
+	assert.Equal(recorded, replayed)
+
+	err = PostJSON(c, ctx, "/v1/tweets?lang=sv", map[string]string{"text": "other"}, &replayed)
+	assert.Error(err, "requests with other bodies should not match")
+
+	err = PostJSON(c, ctx, "/v1/tweets?lang=en", map[string]string{"text": "hello"}, &replayed)
+	assert.Error(err, "requests with other query should not match")
+}
+
+func TestCassetteRecordAndReplayGzip(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "cassettes")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(gzipEncoding, r.Header.Get("Content-Encoding"))
+		reader, err := gzip.NewReader(r.Body)
+		assert.NoError(err)
+		body, _ := ioutil.ReadAll(reader)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", gzipEncoding)
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte(`{"echo":` + string(body) + `}`))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	path := filepath.Join(dir, "gzip.json")
+	match := MatchRules{Method: true, Path: true, Body: true}
+	recorder, err := NewCassette(CassetteConfig{Mode: CassetteRecord, Path: path, Match: match}, nil)
+	assert.NoError(err)
+
+	ctx := context.NewBackground("client-id", "sv", "")
+	c := NewClient("cassette-gzip-test", server.URL, WithTransport(recorder), WithCompression(1))
+	var recorded map[string]interface{}
+	err = PostJSON(c, ctx, "/v1/tweets", map[string]string{"text": "hello"}, &recorded)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"echo": map[string]interface{}{"text": "hello"}}, recorded)
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(err)
+	var file cassetteFile
+	assert.NoError(json.Unmarshal(content, &file))
+	assert.Len(file.Interactions, 1)
+	interaction := file.Interactions[0]
+	assert.Equal(`{"text":"hello"}`, interaction.Request.Body)
+	assert.Equal(`{"echo":{"text":"hello"}}`, interaction.Response.Body)
+	assert.Equal("", interaction.Request.Header.Get("Content-Encoding"))
+	assert.Equal("", interaction.Response.Header.Get("Content-Encoding"))
+
+	player, err := NewCassette(CassetteConfig{Mode: CassetteReplay, Path: path, Match: match}, nil)
+	assert.NoError(err)
+	c = NewClient("cassette-gzip-test", server.URL, WithTransport(player), WithCompression(1), WithRetryPolicy(NoRetries))
+
+	var replayed map[string]interface{}
+	err = PostJSON(c, ctx, "/v1/tweets", map[string]string{"text": "hello"}, &replayed)
+	assert.NoError(err)
+	assert.Equal(recorded, replayed)
+
+	err = PostJSON(c, ctx, "/v1/tweets", map[string]string{"text": "other"}, &replayed)
+	assert.Error(err, "requests with other bodies should not match")
+}
+
+func TestMissingCassette(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "cassettes")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv(CassetteDirEnv, dir)
+	os.Setenv(CassetteModeEnv, string(CassetteReplay))
+	defer os.Unsetenv(CassetteDirEnv)
+	defer os.Unsetenv(CassetteModeEnv)
+
+	c := NewClient("cassette-missing-test", "http://stock-service", WithRetryPolicy(NoRetries))
+	_, err = c.Get(context.NewBackground("client-id", "sv", ""), "/v1/stocks")
+	assert.Error(err)
+	assert.Contains(err.Error(), filepath.Join(dir, "cassette-missing-test.json"))
+}
+
+func TestParseMatchRules(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(DefaultMatchRules, parseMatchRules(""))
+	assert.Equal(MatchRules{Method: true, Body: true}, parseMatchRules("method, BODY"))
+}
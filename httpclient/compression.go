@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const gzipEncoding = "gzip"
+
+// Directions of compressed bytes.
+const (
+	directionRequest  = "request"
+	directionResponse = "response"
+)
+
+// compressRequest gzips the body of a request if it is at least as large as the compression
+// threshold. Streamed bodies, whose size is unknown, are sent uncompressed.
+func (c *client) compressRequest(req *http.Request) error {
+	if c.compressThreshold <= 0 || req.GetBody == nil || req.ContentLength < int64(c.compressThreshold) {
+		return nil
+	}
+	if req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	content, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	writer := gzip.NewWriter(buf)
+	_, err = writer.Write(content)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	compressed := buf.Bytes()
+	req.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", gzipEncoding)
+
+	rpcUncompressedBytes.WithLabelValues(c.name, directionRequest).Add(float64(len(content)))
+	rpcCompressedBytes.WithLabelValues(c.name, directionRequest).Add(float64(len(compressed)))
+	return nil
+}
+
+// acceptGzip asks for gzip encoded responses unless the caller chose an encoding or range.
+// Since the header is then set by the client the transport leaves the response
+// compressed, and decompressResponse takes care of it.
+func acceptGzip(req *http.Request) {
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+		req.Header.Set("Accept-Encoding", gzipEncoding)
+	}
+}
+
+// decompressResponse transparently decompresses gzip encoded responses,
+// also when the caller set the Accept-Encoding header.
+func (c *client) decompressResponse(res *http.Response) {
+	if res.Body == nil || !strings.EqualFold(res.Header.Get("Content-Encoding"), gzipEncoding) {
+		return
+	}
+
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+	res.Body = &gzipBody{
+		client:     c.name,
+		body:       res.Body,
+		compressed: &countingReader{reader: res.Body},
+	}
+}
+
+// gzipBody decompresses a response body and counts the compressed and uncompressed bytes read.
+// The gzip reader is created on the first read, since creating it reads the gzip header.
+type gzipBody struct {
+	client     string
+	body       io.ReadCloser
+	compressed *countingReader
+	reader     *gzip.Reader
+	err        error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = gzip.NewReader(b.compressed)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.reader.Read(p)
+	rpcUncompressedBytes.WithLabelValues(b.client, directionResponse).Add(float64(n))
+	rpcCompressedBytes.WithLabelValues(b.client, directionResponse).Add(float64(b.compressed.take()))
+	return n, err
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
+
+// countingReader counts the bytes read since the count was last taken.
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += n
+	return n, err
+}
+
+func (r *countingReader) take() int {
+	n := r.count
+	r.count = 0
+	return n
+}
@@ -0,0 +1,111 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(r.Body)
+			assert.NoError(err)
+			body = reader
+		}
+
+		content, _ := ioutil.ReadAll(body)
+		w.Header().Set("X-Content-Encoding", r.Header.Get("Content-Encoding"))
+		w.Header().Set("X-Body-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClient("compression-test", server.URL, WithCompression(100), WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Put(ctx, "/v1/tweets", Raw([]byte(strings.Repeat("a", 10)), "text/plain"))
+	assert.NoError(err)
+	assert.Equal("", res.Header.Get("X-Content-Encoding"))
+
+	before := testutil.ToFloat64(rpcUncompressedBytes.WithLabelValues("compression-test", directionRequest))
+	res, err = c.Put(ctx, "/v1/tweets", Raw([]byte(strings.Repeat("a", 500)), "text/plain"))
+	assert.NoError(err)
+	assert.Equal("gzip", res.Header.Get("X-Content-Encoding"))
+	assert.Equal("500", res.Header.Get("X-Body-Length"))
+	assert.Equal(float64(500), testutil.ToFloat64(rpcUncompressedBytes.WithLabelValues("compression-test", directionRequest))-before)
+}
+
+func TestDecompressResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("gzip", r.Header.Get("Accept-Encoding"))
+
+		buf := new(bytes.Buffer)
+		writer := gzip.NewWriter(buf)
+		writer.Write([]byte(`{"symbol":"AAPL"}`))
+		writer.Close()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	ctx := context.NewBackground("client-id", "sv", "")
+	for _, c := range []Client{
+		NewClient("decompression-test", server.URL),
+		NewClient("decompression-test", server.URL, WithHeader("Accept-Encoding", "gzip")),
+	} {
+		before := testutil.ToFloat64(rpcUncompressedBytes.WithLabelValues("decompression-test", directionResponse))
+
+		var stock struct {
+			Symbol string `json:"symbol"`
+		}
+		err := GetJSON(c, ctx, "/v1/stocks/AAPL", &stock)
+		assert.NoError(err)
+		assert.Equal("AAPL", stock.Symbol)
+
+		after := testutil.ToFloat64(rpcUncompressedBytes.WithLabelValues("decompression-test", directionResponse))
+		assert.Equal(float64(len(`{"symbol":"AAPL"}`)), after-before)
+	}
+}
+
+func TestCompressRequestToRouter(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	r := httputil.NewRouter(func() error { return nil })
+	r.POST("/v1/tweets", func(c *gin.Context) {
+		var body map[string]string
+		if err := c.BindJSON(&body); err != nil {
+			return
+		}
+		c.JSON(http.StatusOK, body)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	c := NewClient("compression-router-test", server.URL, WithCompression(10), WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	var echoed map[string]string
+	err := PostJSON(c, ctx, "/v1/tweets", map[string]string{"text": strings.Repeat("a", 100)}, &echoed)
+	assert.NoError(err)
+	assert.Equal(strings.Repeat("a", 100), echoed["text"])
+}
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// Accepted content types of XML documents, e.g. RSS and Atom feeds.
+const acceptXML = "application/xml, text/xml, application/rss+xml, application/atom+xml"
+
+// GetXML performs a GET request accepting XML and decodes the response into out.
+func GetXML(c Client, ctx *context.Context, path string, out interface{}) error {
+	res, err := c.Get(WithCallOptions(ctx, Accept(acceptXML)), path)
+	if err != nil {
+		return err
+	}
+
+	return DecodeXML(ctx, res, out)
+}
+
+// DecodeXML decodes an XML response body into out and closes the body.
+// Responses without content (204) leave out untouched.
+func DecodeXML(ctx *context.Context, res *http.Response, out interface{}) error {
+	return decodeBody(ctx, res, out, isXML, func(r io.Reader, v interface{}) error {
+		return xml.NewDecoder(r).Decode(v)
+	})
+}
+
+// Decode decodes a response body into out based on its Content-Type and closes the body.
+// JSON and XML documents are unmarshalled into out, while a *[]byte or *string
+// out receives the raw body regardless of the Content-Type.
+func Decode(ctx *context.Context, res *http.Response, out interface{}) error {
+	switch out.(type) {
+	case *[]byte, *string:
+		return decodeBody(ctx, res, out, anyContentType, readRaw)
+	}
+
+	if isXML(res.Header.Get("Content-Type")) {
+		return DecodeXML(ctx, res, out)
+	}
+
+	return DecodeJSON(ctx, res, out)
+}
+
+type unmarshalFunc func(r io.Reader, out interface{}) error
+
+// decodeBody checks the Content-Type of a response and decodes its body into out.
+func decodeBody(ctx *context.Context, res *http.Response, out interface{}, accepts func(string) bool, unmarshal unmarshalFunc) error {
+	defer drainAndClose(res)
+	if res.StatusCode == http.StatusNoContent || out == nil {
+		return nil
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	if !accepts(contentType) {
+		message := fmt.Sprintf("Unexpected Content-Type in downstream response. requestId=[%s] status=[%d] contentType=[%s]", ctx.ID, res.StatusCode, contentType)
+		return httputil.BadGateway(message)
+	}
+
+	err := unmarshal(res.Body, out)
+	if err != nil {
+		message := fmt.Sprintf("Failed to decode downstream response. requestId=[%s] status=[%d] type=[%T] err=[%s]", ctx.ID, res.StatusCode, out, err)
+		return httputil.BadGateway(message)
+	}
+
+	return nil
+}
+
+func readRaw(r io.Reader, out interface{}) error {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	switch v := out.(type) {
+	case *[]byte:
+		*v = content
+	case *string:
+		*v = string(content)
+	}
+
+	return nil
+}
+
+func anyContentType(contentType string) bool {
+	return true
+}
+
+// isXML checks if a Content-Type header describes an XML document.
+func isXML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,78 @@
+package httpclient
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Items   []struct {
+		Title string `xml:"title"`
+	} `xml:"channel>item"`
+}
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.rss":
+			accept = r.Header.Get("Accept")
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+			w.Write([]byte(`<rss><channel><item><title>Apple beats estimates</title></item></channel></rss>`))
+		case "/stock":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"symbol":"AAPL"}`))
+		case "/symbols.csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte("AAPL,TSLA"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("decode-test", server.URL)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	var feed rssFeed
+	err := GetXML(c, ctx, "/feed.rss", &feed)
+	assert.NoError(err)
+	assert.Len(feed.Items, 1)
+	assert.Equal("Apple beats estimates", feed.Items[0].Title)
+	assert.Equal(acceptXML, accept)
+
+	res, err := c.Get(ctx, "/feed.rss")
+	assert.NoError(err)
+	feed = rssFeed{}
+	assert.NoError(Decode(ctx, res, &feed))
+	assert.Len(feed.Items, 1)
+
+	res, err = c.Get(ctx, "/stock")
+	assert.NoError(err)
+	var stock struct {
+		Symbol string `json:"symbol"`
+	}
+	assert.NoError(Decode(ctx, res, &stock))
+	assert.Equal("AAPL", stock.Symbol)
+
+	res, err = c.Get(ctx, "/symbols.csv")
+	assert.NoError(err)
+	var csv string
+	assert.NoError(Decode(ctx, res, &csv))
+	assert.Equal("AAPL,TSLA", csv)
+
+	res, err = c.Get(ctx, "/symbols.csv")
+	assert.NoError(err)
+	err = DecodeXML(ctx, res, &feed)
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+	assert.Contains(httpErr.Message, ctx.ID)
+}
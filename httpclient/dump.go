@@ -0,0 +1,270 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDumpBodySize is the number of body bytes logged per request and response unless configured.
+const DefaultDumpBodySize = 4 << 10
+
+// DefaultRedactedFields are the JSON and form fields whose values are not dumped unless configured.
+var DefaultRedactedFields = []string{
+	"password",
+	"secret",
+	"client_secret",
+	"token",
+	"accessToken",
+	"access_token",
+	"refreshToken",
+	"refresh_token",
+	"email",
+}
+
+// redactedHeaders are never dumped.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// DumpConfig configures the request and response dumps of a client.
+type DumpConfig struct {
+	// Enabled is the initial state of the dump mode.
+	Enabled bool
+	// MaxBodySize is the number of body bytes logged, defaults to DefaultDumpBodySize.
+	MaxBodySize int
+	// RedactHeaders are headers, in addition to Authorization and cookies, whose values are not logged.
+	RedactHeaders []string
+	// RedactFields are JSON fields, at any depth, and form and query parameters whose
+	// values are not logged. Matched case-insensitively, defaults to DefaultRedactedFields.
+	RedactFields []string
+}
+
+// Dumper logs the requests a client sends and the responses it receives, with secrets
+// redacted. It is enabled and disabled at runtime, so that dumps can be switched on
+// while debugging an integration without restarting the service.
+type Dumper struct {
+	enabled     int32
+	maxBodySize int
+	headers     []string
+	fields      map[string]bool
+	fieldsRegex *regexp.Regexp
+}
+
+// NewDumper creates a Dumper, to be passed to a client with WithDumper.
+func NewDumper(cfg DumpConfig) *Dumper {
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = DefaultDumpBodySize
+	}
+	if cfg.RedactFields == nil {
+		cfg.RedactFields = DefaultRedactedFields
+	}
+
+	d := &Dumper{
+		maxBodySize: cfg.MaxBodySize,
+		headers:     append(append([]string(nil), redactedHeaders...), cfg.RedactHeaders...),
+		fields:      make(map[string]bool, len(cfg.RedactFields)),
+	}
+	if cfg.Enabled {
+		d.Enable()
+	}
+
+	names := make([]string, 0, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		d.fields[strings.ToLower(field)] = true
+		names = append(names, regexp.QuoteMeta(field))
+	}
+	if len(names) > 0 {
+		// Matches string values, including strings cut off by truncation, and scalar values.
+		d.fieldsRegex = regexp.MustCompile(`(?i)("(?:` + strings.Join(names, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^\s,}\]]+)`)
+	}
+
+	return d
+}
+
+// Enable starts dumping requests and responses.
+func (d *Dumper) Enable() {
+	atomic.StoreInt32(&d.enabled, 1)
+}
+
+// Disable stops dumping requests and responses.
+func (d *Dumper) Disable() {
+	atomic.StoreInt32(&d.enabled, 0)
+}
+
+// Enabled checks if requests and responses are dumped.
+func (d *Dumper) Enabled() bool {
+	return atomic.LoadInt32(&d.enabled) == 1
+}
+
+// dump logs every attempt while the dumper is enabled. Must run last so that the request is
+// dumped as sent. Up to MaxBodySize bytes of the response body are read before it is returned.
+func (c *client) dump(call *Call, next Invoker) (*http.Response, error) {
+	d := c.dumper
+	if !d.Enabled() {
+		return next(call)
+	}
+
+	req := call.Request
+	log.Infow("Dump of downstream request",
+		"client", c.name,
+		"requestId", call.Ctx.ID,
+		"attempt", call.Attempt,
+		"method", req.Method,
+		"url", d.redactURL(req.URL),
+		"header", redactHeader(req.Header, d.headers),
+		"body", d.requestBody(req))
+
+	start := time.Now()
+	res, err := next(call)
+	latency := time.Since(start)
+	if err != nil {
+		log.Infow("Dump of failed downstream request",
+			"client", c.name,
+			"requestId", call.Ctx.ID,
+			"attempt", call.Attempt,
+			"latency", latency.String(),
+			"error", err)
+		return res, err
+	}
+
+	log.Infow("Dump of downstream response",
+		"client", c.name,
+		"requestId", call.Ctx.ID,
+		"attempt", call.Attempt,
+		"status", res.StatusCode,
+		"latency", latency.String(),
+		"header", redactHeader(res.Header, d.headers),
+		"body", d.responseBody(res))
+	return res, nil
+}
+
+// requestBody reads the start of a request body without consuming it. Streamed bodies are not dumped.
+func (d *Dumper) requestBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+	if req.GetBody == nil {
+		return "[streamed body]"
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return "[unreadable body]"
+	}
+	defer body.Close()
+
+	var reader io.Reader = body
+	if strings.EqualFold(req.Header.Get("Content-Encoding"), gzipEncoding) {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return "[unreadable body]"
+		}
+		reader = gzipReader
+	}
+
+	content, _ := ioutil.ReadAll(io.LimitReader(reader, int64(d.maxBodySize)+1))
+	return d.formatBody(content, req.Header.Get("Content-Type"))
+}
+
+// responseBody reads the start of a response body and puts it back in front of the remaining body.
+func (d *Dumper) responseBody(res *http.Response) string {
+	if res.Body == nil || res.Body == http.NoBody {
+		return ""
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(res.Body, int64(d.maxBodySize)+1))
+	res.Body = &prefixedBody{
+		Reader: io.MultiReader(bytes.NewReader(content), &errorReader{reader: res.Body, err: err}),
+		body:   res.Body,
+	}
+
+	return d.formatBody(content, res.Header.Get("Content-Type"))
+}
+
+// formatBody redacts and truncates a body for logging.
+func (d *Dumper) formatBody(content []byte, contentType string) string {
+	truncated := len(content) > d.maxBodySize
+	if truncated {
+		content = content[:d.maxBodySize]
+	}
+
+	var body string
+	switch {
+	case isJSON(contentType) || isJSONStream(contentType):
+		body = d.redactJSON(string(content))
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		body = d.redactForm(string(content))
+	case strings.HasPrefix(contentType, "text/") || isXML(contentType):
+		body = string(content)
+	default:
+		return "[binary body]"
+	}
+
+	if truncated {
+		body += "...[truncated]"
+	}
+	return body
+}
+
+func (d *Dumper) redactJSON(body string) string {
+	if d.fieldsRegex == nil {
+		return body
+	}
+	return d.fieldsRegex.ReplaceAllString(body, `$1"`+redactedValue+`"`)
+}
+
+func (d *Dumper) redactForm(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return "[unparsable form]"
+	}
+	return d.redactValues(values).Encode()
+}
+
+func (d *Dumper) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	redacted := *u
+	redacted.RawQuery = d.redactValues(u.Query()).Encode()
+	return redacted.String()
+}
+
+func (d *Dumper) redactValues(values url.Values) url.Values {
+	for key := range values {
+		if d.fields[strings.ToLower(key)] {
+			values[key] = []string{redactedValue}
+		}
+	}
+	return values
+}
+
+// prefixedBody is a response body that has had its start read for a dump.
+type prefixedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *prefixedBody) Close() error {
+	return b.body.Close()
+}
+
+// errorReader replays an error hit while reading the start of a body, or continues reading the body.
+type errorReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.reader.Read(p)
+}
@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDumper(t *testing.T) {
+	assert := assert.New(t)
+
+	core, logs := observer.New(zap.InfoLevel)
+	defaultLog := log
+	log = zap.New(core).Sugar()
+	defer func() { log = defaultLog }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-session")
+		w.Write([]byte(`{"user":{"name":"Anna","email":"anna@example.com"},"accessToken":"secret-token","bio":"` + strings.Repeat("a", 100) + `"}`))
+	}))
+	defer server.Close()
+
+	dumper := NewDumper(DumpConfig{MaxBodySize: 80})
+	c := NewClient("dump-test", server.URL, WithDumper(dumper), WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "user-token")
+
+	res, err := c.Post(ctx, "/v1/users?token=secret-query&page=1", map[string]string{"password": "secret-password", "name": "Anna"})
+	assert.NoError(err)
+	res.Body.Close()
+	assert.Equal(0, logs.Len())
+
+	dumper.Enable()
+	res, err = c.Post(ctx, "/v1/users?token=secret-query&page=1", map[string]string{"password": "secret-password", "name": "Anna"})
+	assert.NoError(err)
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NoError(err)
+	assert.Contains(string(body), `"accessToken":"secret-token"`)
+
+	dumps := logs.TakeAll()
+	assert.Len(dumps, 2)
+	for _, dump := range dumps {
+		entry := dump.ContextMap()
+		for _, secret := range []string{"secret-password", "secret-query", "secret-token", "secret-session", "user-token", "anna@example.com"} {
+			for key, value := range entry {
+				assert.NotContains(fmt.Sprint(value), secret, "%s: %s leaked in %s", dump.Message, secret, key)
+			}
+		}
+	}
+
+	request := dumps[0].ContextMap()
+	assert.Equal(`{"name":"Anna","password":"REDACTED"}`, request["body"])
+	assert.Contains(request["url"], "page=1")
+	assert.Equal("REDACTED", request["header"].(http.Header).Get("Authorization"))
+
+	response := dumps[1].ContextMap()
+	assert.Equal(int64(http.StatusOK), response["status"])
+	assert.True(strings.HasSuffix(response["body"].(string), "...[truncated]"))
+	assert.Contains(response["body"], `"email":"REDACTED"`)
+
+	dumper.Disable()
+	res, err = c.Get(ctx, "/v1/users")
+	assert.NoError(err)
+	res.Body.Close()
+	assert.Equal(0, logs.Len())
+}
@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// maxErrorBodySize is the largest downstream error body that is read.
+const maxErrorBodySize = 64 << 10
+
+// ErrorPolicy decides how error responses from downstream services are returned to callers.
+type ErrorPolicy int
+
+// Error policies.
+const (
+	// WrapErrors returns all downstream error responses as 502 Bad Gateway.
+	WrapErrors ErrorPolicy = iota
+	// PassThroughClientErrors keeps the status and message of downstream 4xx
+	// responses and wraps all other error responses as 502 Bad Gateway.
+	PassThroughClientErrors
+)
+
+// RemoteError is an error response returned by a downstream service. It is
+// wrapped in the *httputil.Error returned by the client and can be
+// extracted with errors.As.
+type RemoteError struct {
+	Client     string
+	StatusCode int
+	ErrorID    string
+	Message    string
+	Path       string
+	RequestID  string
+}
+
+func (err *RemoteError) Error() string {
+	return fmt.Sprintf("RemoteError(client=%s, status=%d, errorId=%s, path=%s, requestId=%s message=[%s])",
+		err.Client, err.StatusCode, err.ErrorID, err.Path, err.RequestID, err.Message)
+}
+
+// wrapError converts a failed call into a *httputil.Error. The response body, if any, is drained and closed.
+func (c *client) wrapError(ctx *context.Context, res *http.Response, err error) error {
+	defer drainAndClose(res)
+	if httpErr, ok := err.(*httputil.Error); ok {
+		return httpErr
+	}
+
+	if res == nil && isTimeout(err) {
+		message := fmt.Sprintf("Downstream request timed out. requestId=[%s] err=[%s]", ctx.ID, err)
+		return httputil.GatewayTimeout(message)
+	}
+
+	if res == nil {
+		message := fmt.Sprintf("Downstream request failed with no response. requestId=[%s] err=[%s]", ctx.ID, err)
+		return httputil.BadGateway(message)
+	}
+
+	remoteErr := c.parseRemoteError(ctx, res)
+
+	var httpErr *httputil.Error
+	if c.errorPolicy == PassThroughClientErrors && res.StatusCode >= 400 && res.StatusCode < 500 {
+		httpErr = httputil.NewError(remoteErr.Message, res.StatusCode)
+	} else {
+		message := fmt.Sprintf("Downstream failed. requestId=[%s] status=[%d] errorId=[%s] message=[%s]", ctx.ID, res.StatusCode, remoteErr.ErrorID, remoteErr.Message)
+		httpErr = httputil.BadGateway(message)
+	}
+
+	httpErr.Err = remoteErr
+	return httpErr
+}
+
+// parseRemoteError reads an error response from a downstream service. Falls back
+// to using the raw body as message if the response is not a httputil.ErrorResponse.
+func (c *client) parseRemoteError(ctx *context.Context, res *http.Response) *RemoteError {
+	remoteErr := &RemoteError{
+		Client:     c.name,
+		StatusCode: res.StatusCode,
+		RequestID:  ctx.ID,
+	}
+	if res.Request != nil {
+		remoteErr.Path = res.Request.URL.Path
+	}
+
+	if res.Body == nil {
+		return remoteErr
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	if err != nil {
+		log.Warnw("Failed to read remoteError", "client", c.name, "requestId", ctx.ID, "status", res.StatusCode, "error", err)
+		return remoteErr
+	}
+
+	var errorResponse httputil.ErrorResponse
+	err = json.Unmarshal(body, &errorResponse)
+	if err != nil {
+		log.Warnw("Failed to parse remoteError", "client", c.name, "requestId", ctx.ID, "status", res.StatusCode, "error", err)
+		remoteErr.Message = strings.TrimSpace(string(body))
+		return remoteErr
+	}
+
+	remoteErr.ErrorID = errorResponse.ErrorID
+	remoteErr.Message = errorResponse.Message
+	if errorResponse.Path != "" {
+		remoteErr.Path = errorResponse.Path
+	}
+	if errorResponse.RequestID != "" {
+		remoteErr.RequestID = errorResponse.RequestID
+	}
+
+	return remoteErr
+}
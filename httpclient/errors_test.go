@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteError(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/stocks/MISSING":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(httputil.ErrorResponse{
+				ErrorID:    "error-id",
+				Message:    "No such stock",
+				StatusCode: http.StatusNotFound,
+				Path:       r.URL.Path,
+				RequestID:  r.Header.Get(httputil.RequestIDHeader),
+			})
+		case "/v1/stocks/BROKEN":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Internal failure\n"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.NewBackground("client-id", "sv", "")
+	c := NewClient("remote-error-test", server.URL, WithRetryPolicy(NoRetries))
+
+	_, err := c.Get(ctx, "/v1/stocks/MISSING")
+	var httpErr *httputil.Error
+	assert.True(errors.As(err, &httpErr))
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+
+	var remoteErr *RemoteError
+	assert.True(errors.As(err, &remoteErr))
+	assert.Equal("remote-error-test", remoteErr.Client)
+	assert.Equal(http.StatusNotFound, remoteErr.StatusCode)
+	assert.Equal("error-id", remoteErr.ErrorID)
+	assert.Equal("No such stock", remoteErr.Message)
+	assert.Equal("/v1/stocks/MISSING", remoteErr.Path)
+	assert.Equal(ctx.ID, remoteErr.RequestID)
+
+	c = NewClient("remote-error-test", server.URL, WithRetryPolicy(NoRetries), WithErrorPolicy(PassThroughClientErrors))
+
+	_, err = c.Get(ctx, "/v1/stocks/MISSING")
+	assert.True(errors.As(err, &httpErr))
+	assert.Equal(http.StatusNotFound, httpErr.StatusCode)
+	assert.Equal("No such stock", httpErr.Message)
+
+	_, err = c.Get(ctx, "/v1/stocks/BROKEN")
+	assert.True(errors.As(err, &httpErr))
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+	assert.True(errors.As(err, &remoteErr))
+	assert.Equal(http.StatusInternalServerError, remoteErr.StatusCode)
+	assert.Equal("Internal failure", remoteErr.Message)
+	assert.Equal("/v1/stocks/BROKEN", remoteErr.Path)
+}
@@ -1,19 +1,18 @@
 package httpclient
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mimir-news/mimir-go/context"
 	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/mimir-news/mimir-go/id"
 	"github.com/mimir-news/mimir-go/logger"
+	"github.com/mimir-news/mimir-go/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -27,14 +26,77 @@ var (
 			Name: "rpc_requests_total",
 			Help: "The total number of remote procedure calls",
 		},
-		[]string{"endpoint", "method", "status"},
+		[]string{"client", "endpoint", "upstream", "method", "status", "attempt"},
 	)
 	rpcLatency = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "rpc_request_latency_ms",
 			Help: "Remote procedure call duration in milliseconds",
 		},
-		[]string{"endpoint", "method", "status"},
+		[]string{"client", "endpoint", "upstream", "method", "status"},
+	)
+	rpcBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rpc_circuit_breaker_state",
+			Help: "State of the circuit breaker per downstream client (0 = closed, 1 = half-open, 2 = open)",
+		},
+		[]string{"client"},
+	)
+	rpcCacheTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_cache_requests_total",
+			Help: "The total number of cacheable remote procedure calls by cache result (hit, miss, revalidated)",
+		},
+		[]string{"client", "result"},
+	)
+	rpcRateLimiterWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "rpc_rate_limiter_wait_ms",
+			Help: "Time spent waiting for the client side rate limiter in milliseconds",
+		},
+		[]string{"client"},
+	)
+	rpcDeduplicatedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_deduplicated_requests_total",
+			Help: "The total number of GET requests that shared the response of an identical request in flight",
+		},
+		[]string{"client"},
+	)
+	rpcBulkheadInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rpc_bulkhead_in_flight",
+			Help: "The number of requests in flight per downstream client with a bulkhead",
+		},
+		[]string{"client"},
+	)
+	rpcBulkheadQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rpc_bulkhead_queued",
+			Help: "The number of calls waiting for a bulkhead slot per downstream client",
+		},
+		[]string{"client"},
+	)
+	rpcCompressedBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_compressed_bytes_total",
+			Help: "The total number of gzip compressed bytes sent and received by direction (request, response)",
+		},
+		[]string{"client", "direction"},
+	)
+	rpcUncompressedBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_uncompressed_bytes_total",
+			Help: "The total number of bytes sent and received with gzip compression, before compression",
+		},
+		[]string{"client", "direction"},
+	)
+	rpcLeakedBodies = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_leaked_response_bodies_total",
+			Help: "The total number of response bodies garbage collected without being closed, counted in debug mode",
+		},
+		[]string{"client"},
 	)
 )
 
@@ -47,26 +109,111 @@ type Client interface {
 	Request(ctx *context.Context, path, method string, body interface{}) (*http.Response, error)
 }
 
-type remoteError struct {
-	Code    int    `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
-}
-
 type client struct {
-	baseURL          string
-	name             string
-	httpClient       *http.Client
-	warningThreshold time.Duration
+	baseURL           string
+	name              string
+	httpClient        *http.Client
+	warningThreshold  time.Duration
+	retryPolicy       RetryPolicy
+	breaker           *circuitBreaker
+	timeout           time.Duration
+	transport         http.RoundTripper
+	headers           http.Header
+	userAgent         string
+	interceptors      []Interceptor
+	skipDefaults      bool
+	errorPolicy       ErrorPolicy
+	cache             *responseCache
+	limiters          []*rateLimiter
+	balancer          *Balancer
+	compressThreshold int
+	tracer            *tracing.Tracer
+	singleflight      *singleflight
+	bulkhead          *bulkhead
+	idempotencyKeys   bool
+	tokenSource       TokenSource
+	authMode          AuthMode
+	signingKeys       httputil.KeyStore
+	maxResponseSize   int64
+	leakDetection     bool
+	dumper            *Dumper
+	execute           Invoker
+	invoke            Invoker
 }
 
+// Default client settings.
+const (
+	DefaultTimeout          = 30 * time.Second
+	DefaultWarningThreshold = time.Second
+)
+
 // New creates a httpclient.
-func New(name, baseURL string, threshold time.Duration) Client {
-	return &client{
+func New(name, baseURL string, threshold time.Duration, opts ...Option) Client {
+	return NewClient(name, baseURL, append([]Option{WithWarningThreshold(threshold)}, opts...)...)
+}
+
+// NewClient creates a httpclient configured by the supplied options.
+func NewClient(name, baseURL string, opts ...Option) Client {
+	c := &client{
 		name:             name,
 		baseURL:          baseURL,
 		httpClient:       http.DefaultClient,
-		warningThreshold: threshold,
+		warningThreshold: DefaultWarningThreshold,
+		retryPolicy:      DefaultRetryPolicy,
+		timeout:          DefaultTimeout,
+		headers:          make(http.Header),
+		leakDetection:    debugFromEnv(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.transport != nil {
+		httpClient := *c.httpClient
+		httpClient.Transport = c.transport
+		c.httpClient = &httpClient
+	}
+
+	if cassette := cassetteFromEnv(c.name, c.httpClient.Transport); cassette != nil {
+		httpClient := *c.httpClient
+		httpClient.Transport = cassette
+		c.httpClient = &httpClient
+	}
+
+	interceptors := c.interceptors
+	if c.tokenSource != nil {
+		interceptors = append([]Interceptor{c.authenticate}, interceptors...)
+	}
+	if !c.skipDefaults {
+		interceptors = append(c.defaultInterceptors(), interceptors...)
+	}
+	if c.signingKeys != nil {
+		interceptors = append(interceptors, c.sign)
+	}
+	if c.dumper != nil {
+		interceptors = append(interceptors, c.dump)
 	}
+	c.invoke = chain(interceptors, c.send)
+	c.execute = chain(c.callInterceptors(), c.retry)
+
+	return c
+}
+
+// callInterceptors creates the interceptors that run once per call, before any retries.
+func (c *client) callInterceptors() []Interceptor {
+	interceptors := make([]Interceptor, 0)
+	if c.cache != nil {
+		interceptors = append(interceptors, c.cache.intercept)
+	}
+	if c.singleflight != nil {
+		interceptors = append(interceptors, c.singleflight.intercept)
+	}
+	if c.bulkhead != nil {
+		interceptors = append(interceptors, c.bulkhead.intercept)
+	}
+
+	return interceptors
 }
 
 func (c *client) Get(ctx *context.Context, path string) (*http.Response, error) {
@@ -90,27 +237,55 @@ func (c *client) Delete(ctx *context.Context, path string) (*http.Response, erro
 }
 
 func (c *client) Request(ctx *context.Context, path, method string, body interface{}) (*http.Response, error) {
-	startTime := time.Now()
-	timer := createTimer(startTime)
-	defer c.logRequestLatency(ctx, startTime, path)
-	req, err := c.createRequest(ctx, path, method, body)
+	params := getCallOptions(ctx).pathParams
+	route := routeOf(path, params)
+	if params != nil {
+		expanded, err := expandPath(path, params)
+		if err != nil {
+			c.logError(ctx, "Failed to expand route template", method, route, err)
+			return nil, err
+		}
+		path = expanded
+	}
+
+	span := c.startSpan(ctx, method, route)
+	req, err := c.createRequest(ctx, path, method, body, span)
 	if err != nil {
+		span.Finish(errorStatus(err))
 		return nil, err
 	}
 
-	res, err := c.httpClient.Do(req)
-	if err != nil || (res != nil && res.StatusCode >= 300) {
-		c.recordMetricsOnError(timer, path, method, res)
-		return nil, c.wrapError(ctx, res, err)
+	req, timeout := c.applyTimeout(req)
+	call := &Call{
+		Ctx:     ctx,
+		Client:  c.name,
+		BaseURL: c.baseURL,
+		Method:  method,
+		Path:    path,
+		Route:   route,
+		Request: req,
+	}
+
+	res, err := c.execute(call)
+	if err != nil || res.StatusCode >= 300 {
+		err = c.wrapError(ctx, res, timeout.wrap(err))
+		span.Finish(errorStatus(err))
+		timeout.cancel()
+		return nil, err
 	}
 
-	c.recordMetrics(timer, path, method, res.StatusCode)
+	timeout.headersReceived()
+	span.Finish(res.StatusCode)
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: timeout.cancel}
+	if c.leakDetection {
+		res = c.trackLeaks(call, res)
+	}
 	return res, nil
 }
 
-func (c *client) createRequest(ctx *context.Context, path, method string, body interface{}) (*http.Request, error) {
+func (c *client) createRequest(ctx *context.Context, path, method string, body interface{}, span *tracing.Span) (*http.Request, error) {
 	fullURL := c.baseURL + path
-	bodyReader, err := createBody(body)
+	bodyReader, contentType, err := createBody(body)
 	if err != nil {
 		c.logError(ctx, "Failed to create request body", method, path, err)
 		return nil, err
@@ -121,91 +296,85 @@ func (c *client) createRequest(ctx *context.Context, path, method string, body i
 		c.logError(ctx, "Failed to create request body", method, path, err)
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+
+	options := getCallOptions(ctx)
+	accept := "application/json"
+	if options.accept != "" {
+		accept = options.accept
+	}
 
-	if ctx.AuthToken != "" {
-		req.Header.Set("Authorization", "Bearer "+ctx.AuthToken)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Content-Type", contentType)
+	if key := c.idempotencyKey(method, options); key != "" {
+		req.Header.Set(httputil.IdempotencyKeyHeader, key)
 	}
+	tracing.Inject(span.Context(), req.Header)
 
-	req.Header.Set("X-ClientID", ctx.ClientID)
-	req.Header.Set(httputil.RequestIDHeader, ctx.ID)
-	req.Header.Set(httputil.AcceptLanguage, ctx.Language)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	err = c.compressRequest(req)
+	if err != nil {
+		c.logError(ctx, "Failed to compress request body", method, path, err)
+		return nil, err
+	}
 
 	return req, nil
 }
 
-func (c *client) wrapError(ctx *context.Context, res *http.Response, err error) error {
-	if res == nil {
-		message := fmt.Sprintf("Downstream request failed with no response. requestId=[%s] err=[%s]", ctx.ID, err)
-		return httputil.BadGateway(message)
+// idempotencyKey returns the key supplied for a call, or a new key for POST
+// requests if the client generates idempotency keys.
+func (c *client) idempotencyKey(method string, options callOptions) string {
+	if options.idempotencyKey != "" {
+		return options.idempotencyKey
 	}
 
-	var remoteErr remoteError
-	parseErr := json.NewDecoder(res.Body).Decode(&remoteErr)
-	if res == nil {
-		log.Warnw("Failed to parse remoteError", "client", c.name, "requestId", ctx.ID, "error", parseErr)
-		message := fmt.Sprintf("Downstream request failed could not parse error. requestId=[%s] code=[%d] err=[%s]", ctx.ID, res.StatusCode, err)
-		return httputil.BadGateway(message)
+	if c.idempotencyKeys && method == http.MethodPost {
+		return id.New()
 	}
 
-	message := fmt.Sprintf("Downstream failed. requestId=[%s] status=[%d] code=[%d] message=[%s]", ctx.ID, res.StatusCode, remoteErr.Code, remoteErr.Message)
-	return httputil.BadGateway(message)
-}
-
-func (c *client) logError(ctx *context.Context, message, method, path string, err error) {
-	log.Errorw(message, "client", c.name, "method", method, "url", stripQueryParameters(path), "ctx", ctx, "error", err)
+	return ""
 }
 
-func (c *client) logRequestLatency(ctx *context.Context, startTime time.Time, path string) {
-	duration := time.Now().Sub(startTime)
-	if duration < c.warningThreshold {
-		return
+// send is the innermost invoker of the interceptor chain.
+func (c *client) send(call *Call) (*http.Response, error) {
+	acceptGzip(call.Request)
+	res, err := c.httpClient.Do(call.Request)
+	if err != nil {
+		return res, err
 	}
 
-	latency := fmt.Sprintf("%.2f ms", toMilliseconds(duration))
-	threshold := fmt.Sprintf("%.2f ms", toMilliseconds(c.warningThreshold))
-	log.Warnw("Unusually high latency in service call",
-		"path", stripQueryParameters(path),
-		"requestId", ctx.ID,
-		"clientId", ctx.ClientID,
-		"latency", latency,
-		"warningThreshold", threshold)
-}
-
-func (c *client) recordMetricsOnError(timer calcDuration, path, method string, res *http.Response) {
-	statusCode := http.StatusServiceUnavailable
-	if res != nil {
-		statusCode = res.StatusCode
+	c.decompressResponse(res)
+	if err = c.limitResponse(call, res); err != nil {
+		return nil, err
 	}
 
-	c.recordMetrics(timer, path, method, statusCode)
+	return res, nil
 }
 
-func (c *client) recordMetrics(stopTimer calcDuration, path, method string, statusCode int) {
-	latency := stopTimer()
-	endpoint := stripQueryAndUUIDs(c.baseURL + path)
-	status := strconv.Itoa(statusCode)
+func (c *client) logError(ctx *context.Context, message, method, path string, err error) {
+	log.Errorw(message, "client", c.name, "method", method, "url", stripQueryParameters(path), "ctx", ctx, "error", err)
+}
 
-	rpcsTotal.WithLabelValues(endpoint, method, status).Inc()
-	rpcLatency.WithLabelValues(endpoint, method, status).Observe(latency)
+// statusCode returns the status of a response, or 503 if no response was received.
+func statusCode(res *http.Response) int {
+	if res == nil {
+		return http.StatusServiceUnavailable
+	}
+	return res.StatusCode
 }
 
-func createBody(body interface{}) (io.Reader, error) {
+// createBody creates the request body and its content type, JSON unless the body is a Body.
+func createBody(body interface{}) (io.Reader, string, error) {
 	if body == nil {
-		return nil, nil
+		return nil, "application/json", nil
 	}
 
-	var bodyReader io.Reader
-	if body != nil {
-		bytesBody, err := json.Marshal(body)
-		if err != nil {
-			return nil, err
-		}
-		bodyReader = bytes.NewBuffer(bytesBody)
+	b, ok := body.(Body)
+	if !ok {
+		b = JSON(body)
 	}
 
-	return bodyReader, nil
+	bodyReader, err := b.Reader()
+	return bodyReader, b.ContentType(), err
 }
 
 type calcDuration func() float64
@@ -0,0 +1,281 @@
+package httpclienttest
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// Fixed values of contexts created by NewContext.
+const (
+	RequestID = "test-request-id"
+	ClientID  = "test-client-id"
+	Language  = "sv"
+	AuthToken = "test-auth-token"
+)
+
+// NewContext creates a context with fixed ids, so that the headers sent by a client can be asserted.
+func NewContext() *context.Context {
+	return context.New(stdcontext.Background(), RequestID, ClientID, Language, AuthToken)
+}
+
+// Server is a fake downstream service that serves scripted responses to expected calls.
+type Server struct {
+	*httptest.Server
+	t testing.TB
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+}
+
+// NewServer creates and starts a server. Call AssertExpectations at the end
+// of the test to report unmet and unexpected calls, and Close to stop it.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		t:            t,
+		expectations: make([]*Expectation, 0),
+		unexpected:   make([]string, 0),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Expect adds an expected call. The path may include a query string, in which case the query must match as well.
+func (s *Server) Expect(method, path string) *Expectation {
+	e := &Expectation{
+		method:         method,
+		path:           path,
+		headers:        make(http.Header),
+		times:          1,
+		status:         http.StatusOK,
+		responseHeader: make(http.Header),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expectations = append(s.expectations, e)
+	return e
+}
+
+// AssertExpectations reports expected calls that were not made and calls that were not expected.
+func (s *Server) AssertExpectations() {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.expectations {
+		if e.calls < e.times {
+			s.t.Errorf("httpclienttest: expected %s %s to be called %d time(s), was called %d time(s)", e.method, e.path, e.times, e.calls)
+		}
+	}
+
+	for _, call := range s.unexpected {
+		s.t.Errorf("httpclienttest: unexpected call %s", call)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.t.Errorf("httpclienttest: failed to read request body: %s", err)
+	}
+
+	s.mu.Lock()
+	e, mismatches := s.match(r, body)
+	if e == nil {
+		call := fmt.Sprintf("%s %s", r.Method, r.URL.RequestURI())
+		if len(mismatches) > 0 {
+			call = fmt.Sprintf("%s (%s)", call, strings.Join(mismatches, ", "))
+		}
+		s.unexpected = append(s.unexpected, call)
+	}
+	s.mu.Unlock()
+
+	if e == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	e.respond(w)
+}
+
+// match finds the first expectation with remaining calls matching the request. Must be called with the lock held.
+func (s *Server) match(r *http.Request, body []byte) (*Expectation, []string) {
+	mismatches := make([]string, 0)
+	for _, e := range s.expectations {
+		if e.calls >= e.times || !e.matchesRoute(r) {
+			continue
+		}
+
+		reasons := e.mismatches(r, body)
+		if len(reasons) > 0 {
+			mismatches = append(mismatches, reasons...)
+			continue
+		}
+
+		e.calls++
+		return e, nil
+	}
+
+	return nil, mismatches
+}
+
+// Expectation is an expected call and the response to it.
+type Expectation struct {
+	method      string
+	path        string
+	headers     http.Header
+	bodyMatcher func(body []byte) error
+	times       int
+	calls       int
+
+	status         int
+	responseHeader http.Header
+	responseBody   []byte
+	dropConnection bool
+}
+
+// WithHeader expects the request to carry a header value.
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.headers.Set(key, value)
+	return e
+}
+
+// WithContextHeaders expects the request to carry the headers a client sets from the context.
+func (e *Expectation) WithContextHeaders(ctx *context.Context) *Expectation {
+	e.WithHeader(httputil.RequestIDHeader, ctx.ID)
+	e.WithHeader(httputil.ClientIDHeader, ctx.ClientID)
+	e.WithHeader(httputil.AcceptLanguage, ctx.Language)
+	if ctx.AuthToken != "" {
+		e.WithHeader("Authorization", "Bearer "+ctx.AuthToken)
+	}
+	return e
+}
+
+// WithJSONBody expects the request body to be JSON equal to the supplied value.
+func (e *Expectation) WithJSONBody(expected interface{}) *Expectation {
+	return e.WithBodyMatcher(func(body []byte) error {
+		expectedJSON, err := json.Marshal(expected)
+		if err != nil {
+			return err
+		}
+
+		var want, got interface{}
+		json.Unmarshal(expectedJSON, &want)
+		if err := json.Unmarshal(body, &got); err != nil {
+			return fmt.Errorf("body is not JSON: %s", err)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			return fmt.Errorf("body %s does not equal %s", body, expectedJSON)
+		}
+		return nil
+	})
+}
+
+// WithBodyMatcher expects the matcher to accept the request body.
+func (e *Expectation) WithBodyMatcher(matcher func(body []byte) error) *Expectation {
+	e.bodyMatcher = matcher
+	return e
+}
+
+// Times sets the number of times the call is expected, defaults to one.
+func (e *Expectation) Times(times int) *Expectation {
+	e.times = times
+	return e
+}
+
+// Respond sets the status and raw body of the response.
+func (e *Expectation) Respond(status int, body []byte) *Expectation {
+	e.status = status
+	e.responseBody = body
+	return e
+}
+
+// RespondJSON sets the status of the response and encodes the body as JSON.
+func (e *Expectation) RespondJSON(status int, body interface{}) *Expectation {
+	content, err := json.Marshal(body)
+	if err != nil {
+		panic(fmt.Sprintf("httpclienttest: failed to encode response body: %s", err))
+	}
+
+	e.responseHeader.Set("Content-Type", "application/json")
+	return e.Respond(status, content)
+}
+
+// RespondError responds with a httputil.ErrorResponse, the way mimir services report errors.
+func (e *Expectation) RespondError(status int, message string) *Expectation {
+	return e.RespondJSON(status, httputil.ErrorResponse{
+		ErrorID:    "test-error-id",
+		Message:    message,
+		StatusCode: status,
+		Path:       strings.Split(e.path, "?")[0],
+	})
+}
+
+// RespondHeader adds a header to the response.
+func (e *Expectation) RespondHeader(key, value string) *Expectation {
+	e.responseHeader.Add(key, value)
+	return e
+}
+
+// DropConnection closes the connection without responding, causing a connection error in the client.
+func (e *Expectation) DropConnection() *Expectation {
+	e.dropConnection = true
+	return e
+}
+
+func (e *Expectation) matchesRoute(r *http.Request) bool {
+	if e.method != r.Method {
+		return false
+	}
+
+	if strings.Contains(e.path, "?") {
+		return e.path == r.URL.RequestURI()
+	}
+	return e.path == r.URL.Path
+}
+
+func (e *Expectation) mismatches(r *http.Request, body []byte) []string {
+	mismatches := make([]string, 0)
+	for key := range e.headers {
+		if got := r.Header.Get(key); got != e.headers.Get(key) {
+			mismatches = append(mismatches, fmt.Sprintf("header %s=[%s] expected [%s]", key, got, e.headers.Get(key)))
+		}
+	}
+
+	if e.bodyMatcher != nil {
+		if err := e.bodyMatcher(body); err != nil {
+			mismatches = append(mismatches, err.Error())
+		}
+	}
+
+	return mismatches
+}
+
+func (e *Expectation) respond(w http.ResponseWriter) {
+	if e.dropConnection {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+
+	for key, values := range e.responseHeader {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(e.status)
+	w.Write(e.responseBody)
+}
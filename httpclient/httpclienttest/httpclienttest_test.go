@@ -0,0 +1,94 @@
+package httpclienttest_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/httpclient"
+	"github.com/mimir-news/mimir-go/httpclient/httpclienttest"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type stock struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+	server := httpclienttest.NewServer(t)
+	defer server.Close()
+
+	ctx := httpclienttest.NewContext()
+	server.Expect(http.MethodGet, "/v1/stocks/AAPL").
+		WithContextHeaders(ctx).
+		RespondJSON(http.StatusOK, stock{Symbol: "AAPL", Name: "Apple"})
+	server.Expect(http.MethodPost, "/v1/stocks").
+		WithJSONBody(stock{Symbol: "TSLA", Name: "Tesla"}).
+		RespondError(http.StatusConflict, "stock already exists")
+
+	client := httpclient.NewClient("stock-service", server.URL, httpclient.WithRetryPolicy(httpclient.NoRetries))
+
+	var s stock
+	err := httpclient.GetJSON(client, ctx, "/v1/stocks/AAPL", &s)
+	assert.NoError(err)
+	assert.Equal("Apple", s.Name)
+
+	err = httpclient.PostJSON(client, ctx, "/v1/stocks", stock{Symbol: "TSLA", Name: "Tesla"}, nil)
+	var remoteErr *httpclient.RemoteError
+	assert.True(errors.As(err, &remoteErr))
+	assert.Equal(http.StatusConflict, remoteErr.StatusCode)
+	assert.Equal("stock already exists", remoteErr.Message)
+
+	server.AssertExpectations()
+}
+
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestServerReportsUnmetAndUnexpectedCalls(t *testing.T) {
+	assert := assert.New(t)
+	rt := &recordingT{TB: t}
+	server := httpclienttest.NewServer(rt)
+	defer server.Close()
+
+	server.Expect(http.MethodGet, "/v1/stocks/AAPL").
+		WithHeader(httputil.RequestIDHeader, "other-request-id").
+		Times(2)
+
+	client := httpclient.NewClient("stock-service", server.URL, httpclient.WithRetryPolicy(httpclient.NoRetries))
+	_, err := client.Get(httpclienttest.NewContext(), "/v1/stocks/AAPL")
+	assert.Error(err)
+
+	server.AssertExpectations()
+	assert.Len(rt.errors, 2)
+	assert.Contains(rt.errors[0], "to be called 2 time(s), was called 0 time(s)")
+	assert.Contains(rt.errors[1], "unexpected call GET /v1/stocks/AAPL")
+	assert.Contains(rt.errors[1], "header X-Request-Id=[test-request-id] expected [other-request-id]")
+}
+
+func TestDropConnection(t *testing.T) {
+	server := httpclienttest.NewServer(t)
+	defer server.Close()
+
+	server.Expect(http.MethodGet, "/v1/stocks").DropConnection()
+
+	client := httpclient.NewClient("stock-service", server.URL, httpclient.WithRetryPolicy(httpclient.NoRetries))
+	_, err := client.Get(httpclienttest.NewContext(), "/v1/stocks")
+
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	server.AssertExpectations()
+}
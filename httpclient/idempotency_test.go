@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	keys := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(httputil.IdempotencyKeyHeader))
+		attempt := len(keys)
+		mu.Unlock()
+
+		if attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	c := NewClient("idempotency-test", server.URL, WithIdempotencyKeys(), WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Post(ctx, "/v1/orders", map[string]string{"symbol": "AAPL"})
+	assert.NoError(err)
+	assert.Equal(http.StatusCreated, res.StatusCode)
+	assert.Len(keys, 2)
+	assert.NotEmpty(keys[0])
+	assert.Equal(keys[0], keys[1])
+
+	keys = keys[:0]
+	_, err = c.Post(WithCallOptions(ctx, IdempotencyKey("order-1")), "/v1/orders", nil)
+	assert.NoError(err)
+	assert.Equal([]string{"order-1", "order-1"}, keys)
+
+	keys = keys[:0]
+	_, err = NewClient("idempotency-test", server.URL, WithRetryPolicy(testRetryPolicy)).Post(ctx, "/v1/orders", nil)
+	assert.Error(err)
+	assert.Equal([]string{""}, keys)
+}
@@ -0,0 +1,135 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// Call is a single attempt of an outgoing request passing through the interceptor chain.
+// Route is the route template of the call, or its path without query and UUIDs.
+type Call struct {
+	Ctx     *context.Context
+	Client  string
+	BaseURL string
+	Method  string
+	Path    string
+	Route   string
+	Attempt int
+	Request *http.Request
+}
+
+// Invoker sends a call downstream and returns the response.
+type Invoker func(call *Call) (*http.Response, error)
+
+// Interceptor intercepts the calls made by a client. It may inspect or modify
+// call.Request before handing the call to next, and inspect or replace the
+// response or error that next returns. An interceptor that does not call
+// next must return either a response or an error.
+type Interceptor func(call *Call, next Invoker) (*http.Response, error)
+
+// chain wraps the invoker in the interceptors, with the first interceptor being the outermost.
+func chain(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(call *Call) (*http.Response, error) {
+			return interceptor(call, next)
+		}
+	}
+
+	return invoker
+}
+
+// defaultInterceptors creates the interceptors used unless WithoutDefaultInterceptors is set.
+func (c *client) defaultInterceptors() []Interceptor {
+	headers := make(http.Header)
+	for key, values := range c.headers {
+		headers[key] = values
+	}
+	if c.userAgent != "" {
+		headers.Set("User-Agent", c.userAgent)
+	}
+
+	return []Interceptor{
+		ContextHeaders(),
+		StaticHeaders(headers),
+		LatencyWarning(c.warningThreshold),
+		Metrics(),
+	}
+}
+
+// ContextHeaders sets the auth token, client id, request id and language of the call context as request headers.
+func ContextHeaders() Interceptor {
+	return func(call *Call, next Invoker) (*http.Response, error) {
+		ctx, req := call.Ctx, call.Request
+		if ctx.AuthToken != "" {
+			req.Header.Set("Authorization", "Bearer "+ctx.AuthToken)
+		}
+
+		req.Header.Set(httputil.ClientIDHeader, ctx.ClientID)
+		req.Header.Set(httputil.RequestIDHeader, ctx.ID)
+		req.Header.Set(httputil.AcceptLanguage, ctx.Language)
+		return next(call)
+	}
+}
+
+// StaticHeaders sets the supplied headers on every request, replacing existing values.
+func StaticHeaders(headers http.Header) Interceptor {
+	return func(call *Call, next Invoker) (*http.Response, error) {
+		for key, values := range headers {
+			call.Request.Header[key] = append([]string(nil), values...)
+		}
+		return next(call)
+	}
+}
+
+// LatencyWarning logs a warning for calls slower than the threshold.
+func LatencyWarning(threshold time.Duration) Interceptor {
+	return func(call *Call, next Invoker) (*http.Response, error) {
+		startTime := time.Now()
+		res, err := next(call)
+
+		duration := time.Now().Sub(startTime)
+		if duration < threshold {
+			return res, err
+		}
+
+		latency := fmt.Sprintf("%.2f ms", toMilliseconds(duration))
+		warningThreshold := fmt.Sprintf("%.2f ms", toMilliseconds(threshold))
+		log.Warnw("Unusually high latency in service call",
+			"client", call.Client,
+			"path", stripQueryParameters(call.Path),
+			"requestId", call.Ctx.ID,
+			"clientId", call.Ctx.ClientID,
+			"attempt", call.Attempt,
+			"latency", latency,
+			"warningThreshold", warningThreshold)
+		return res, err
+	}
+}
+
+// Metrics records the count and latency of calls in prometheus. The endpoint
+// label holds the route of the call and the upstream label the host the request was sent to.
+func Metrics() Interceptor {
+	return func(call *Call, next Invoker) (*http.Response, error) {
+		stopTimer := createTimer(time.Now())
+		res, err := next(call)
+
+		status := statusCode(res)
+		if res == nil && isTimeout(err) {
+			status = http.StatusGatewayTimeout
+		}
+
+		latency := stopTimer()
+		endpoint := call.BaseURL + call.Route
+		upstream := call.Request.URL.Host
+		statusLabel := strconv.Itoa(status)
+		rpcsTotal.WithLabelValues(call.Client, endpoint, upstream, call.Method, statusLabel, strconv.Itoa(call.Attempt)).Inc()
+		rpcLatency.WithLabelValues(call.Client, endpoint, upstream, call.Method, statusLabel).Observe(latency)
+		return res, err
+	}
+}
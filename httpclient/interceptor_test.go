@@ -0,0 +1,102 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptorOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Order", r.Header.Get("X-Order"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	appendOrder := func(name string) Interceptor {
+		return func(call *Call, next Invoker) (*http.Response, error) {
+			order := call.Request.Header.Get("X-Order")
+			call.Request.Header.Set("X-Order", order+name)
+			res, err := next(call)
+			if err == nil {
+				res.Header.Set("X-Order", res.Header.Get("X-Order")+"|"+name)
+			}
+			return res, err
+		}
+	}
+
+	c := NewClient("interceptor-test", server.URL, WithInterceptors(appendOrder("a"), appendOrder("b")))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Get(ctx, "/v1/things")
+	assert.NoError(err)
+	res.Body.Close()
+	assert.Equal("ab|b|a", res.Header.Get("X-Order"))
+}
+
+func TestInterceptorRunsPerAttempt(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	attempts := make([]int, 0)
+	faultInjection := func(call *Call, next Invoker) (*http.Response, error) {
+		attempts = append(attempts, call.Attempt)
+		if call.Attempt == 1 {
+			return nil, errors.New("injected fault")
+		}
+		return next(call)
+	}
+
+	c := NewClient("interceptor-test", server.URL, WithRetryPolicy(testRetryPolicy), WithInterceptors(faultInjection))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Get(ctx, "/v1/things")
+	assert.NoError(err)
+	res.Body.Close()
+	assert.Equal([]int{1, 2}, attempts)
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWithoutDefaultInterceptors(t *testing.T) {
+	assert := assert.New(t)
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.NewBackground("client-id", "sv", "auth-token")
+
+	c := NewClient("interceptor-test", server.URL, WithoutDefaultInterceptors())
+	res, err := c.Get(ctx, "/v1/things")
+	assert.NoError(err)
+	res.Body.Close()
+	assert.Equal("", header.Get("Authorization"))
+	assert.Equal("", header.Get(httputil.RequestIDHeader))
+
+	c = NewClient("interceptor-test", server.URL,
+		WithoutDefaultInterceptors(),
+		WithInterceptors(ContextHeaders(), LatencyWarning(time.Second), Metrics()))
+	res, err = c.Get(ctx, "/v1/things")
+	assert.NoError(err)
+	res.Body.Close()
+	assert.Equal("Bearer auth-token", header.Get("Authorization"))
+	assert.Equal(ctx.ID, header.Get(httputil.RequestIDHeader))
+}
@@ -0,0 +1,59 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mimir-news/mimir-go/context"
+)
+
+// GetJSON performs a GET request and decodes the JSON response into out.
+func GetJSON(c Client, ctx *context.Context, path string, out interface{}) error {
+	return RequestJSON(c, ctx, path, http.MethodGet, nil, out)
+}
+
+// PostJSON performs a POST request and decodes the JSON response into out.
+func PostJSON(c Client, ctx *context.Context, path string, body, out interface{}) error {
+	return RequestJSON(c, ctx, path, http.MethodPost, body, out)
+}
+
+// PutJSON performs a PUT request and decodes the JSON response into out.
+func PutJSON(c Client, ctx *context.Context, path string, body, out interface{}) error {
+	return RequestJSON(c, ctx, path, http.MethodPut, body, out)
+}
+
+// DeleteJSON performs a DELETE request and decodes the JSON response into out.
+func DeleteJSON(c Client, ctx *context.Context, path string, out interface{}) error {
+	return RequestJSON(c, ctx, path, http.MethodDelete, nil, out)
+}
+
+// RequestJSON performs a request and decodes the JSON response into out.
+func RequestJSON(c Client, ctx *context.Context, path, method string, body, out interface{}) error {
+	res, err := c.Request(ctx, path, method, body)
+	if err != nil {
+		return err
+	}
+
+	return DecodeJSON(ctx, res, out)
+}
+
+// DecodeJSON decodes a JSON response body into out and closes the body.
+// Responses without content (204) leave out untouched.
+func DecodeJSON(ctx *context.Context, res *http.Response, out interface{}) error {
+	return decodeBody(ctx, res, out, isJSON, func(r io.Reader, v interface{}) error {
+		return json.NewDecoder(r).Decode(v)
+	})
+}
+
+// isJSON checks if a Content-Type header describes a JSON document.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
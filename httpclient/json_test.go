@@ -0,0 +1,71 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/dto"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/stocks/AAPL":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"name":"Apple Inc.","symbol":"AAPL","description":"Tech"}`))
+		case "/v1/stocks/EMPTY":
+			w.WriteHeader(http.StatusNoContent)
+		case "/v1/stocks/TEXT":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("AAPL"))
+		case "/v1/stocks/BROKEN":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"name":`))
+		}
+	}))
+	defer server.Close()
+
+	c := New("json-test", server.URL, time.Second)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	var stock dto.Stock
+	err := GetJSON(c, ctx, "/v1/stocks/AAPL", &stock)
+	assert.NoError(err)
+	assert.Equal("AAPL", stock.Symbol)
+	assert.Equal("Apple Inc.", stock.Name)
+
+	stock = dto.Stock{Symbol: "unchanged"}
+	err = GetJSON(c, ctx, "/v1/stocks/EMPTY", &stock)
+	assert.NoError(err)
+	assert.Equal("unchanged", stock.Symbol)
+
+	err = GetJSON(c, ctx, "/v1/stocks/TEXT", &stock)
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+	assert.True(strings.Contains(httpErr.Message, ctx.ID))
+
+	err = GetJSON(c, ctx, "/v1/stocks/BROKEN", &stock)
+	httpErr, ok = err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+	assert.True(strings.Contains(httpErr.Message, ctx.ID))
+}
+
+func TestIsJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isJSON("application/json"))
+	assert.True(isJSON("application/json; charset=utf-8"))
+	assert.True(isJSON("application/problem+json"))
+	assert.False(isJSON("text/plain"))
+	assert.False(isJSON(""))
+}
@@ -0,0 +1,48 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"runtime"
+
+	"github.com/mimir-news/mimir-go/environ"
+)
+
+// DebugEnv enables debug mode for all clients when set to true. Debug mode detects
+// leaked response bodies, at the cost of a finalizer per response.
+const DebugEnv = "HTTPCLIENT_DEBUG"
+
+func debugFromEnv() bool {
+	return environ.Get(DebugEnv, "false") == "true"
+}
+
+// trackLeaks wraps the body of a response returned to the caller so that a warning is logged
+// if the body is garbage collected without being closed. The leaked body is then closed,
+// releasing its connection. A copy of the response is returned, since the transport
+// holds on to the original response until its body is closed.
+func (c *client) trackLeaks(call *Call, res *http.Response) *http.Response {
+	body := &trackedBody{ReadCloser: res.Body}
+	client, method, route, requestID := c.name, call.Method, call.Route, call.Ctx.ID
+	runtime.SetFinalizer(body, func(b *trackedBody) {
+		rpcLeakedBodies.WithLabelValues(client).Inc()
+		log.Warnw("Response body garbage collected without being closed",
+			"client", client,
+			"method", method,
+			"path", route,
+			"requestId", requestID)
+		b.ReadCloser.Close()
+	})
+
+	tracked := *res
+	tracked.Body = body
+	return &tracked
+}
+
+type trackedBody struct {
+	io.ReadCloser
+}
+
+func (b *trackedBody) Close() error {
+	runtime.SetFinalizer(b, nil)
+	return b.ReadCloser.Close()
+}
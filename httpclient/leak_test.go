@@ -0,0 +1,42 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeakDetection(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ctx := context.NewBackground("client-id", "sv", "")
+	c := NewClient("leak-test", server.URL, WithLeakDetection(), WithRetryPolicy(testRetryPolicy))
+	leaked := rpcLeakedBodies.WithLabelValues("leak-test")
+	before := testutil.ToFloat64(leaked)
+
+	res, err := c.Get(ctx, "/v1/closed")
+	assert.NoError(err)
+	res.Body.Close()
+
+	_, err = c.Get(ctx, "/v1/leaked")
+	assert.NoError(err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(leaked) < before+1 && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(float64(1), testutil.ToFloat64(leaked)-before)
+}
@@ -0,0 +1,71 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// ErrResponseTooLarge is returned when a downstream response body exceeds the max response size of a client.
+var ErrResponseTooLarge = errors.New("httpclient: response body exceeds max response size")
+
+// limitResponse rejects responses declaring a Content-Length above the max response size and
+// caps the body of other responses, so that reads past the limit fail with ErrResponseTooLarge.
+func (c *client) limitResponse(call *Call, res *http.Response) error {
+	if c.maxResponseSize <= 0 || res.Body == nil {
+		return nil
+	}
+
+	if res.ContentLength > c.maxResponseSize {
+		res.Body.Close()
+		log.Warnw("Downstream response exceeds max response size",
+			"client", c.name,
+			"method", call.Method,
+			"path", call.Route,
+			"requestId", call.Ctx.ID,
+			"contentLength", res.ContentLength,
+			"maxSize", c.maxResponseSize)
+
+		message := fmt.Sprintf("Downstream response too large. requestId=[%s] contentLength=[%d] maxSize=[%d]", call.Ctx.ID, res.ContentLength, c.maxResponseSize)
+		err := httputil.BadGateway(message)
+		err.Err = ErrResponseTooLarge
+		return err
+	}
+
+	res.Body = &limitedBody{
+		ReadCloser: res.Body,
+		remaining:  c.maxResponseSize,
+	}
+	return nil
+}
+
+// limitedBody fails reads once more than the remaining number of bytes have been read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.err = ErrResponseTooLarge
+		return n, b.err
+	}
+
+	b.remaining -= int64(n)
+	return n, err
+}
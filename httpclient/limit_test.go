@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxResponseSize(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("a", 2048)
+		if r.URL.Path == "/v1/chunked" {
+			w.Write([]byte(body[:1024]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[1024:]))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	ctx := context.NewBackground("client-id", "sv", "")
+	c := NewClient("limit-test", server.URL, WithMaxResponseSize(1024), WithRetryPolicy(testRetryPolicy))
+
+	_, err := c.Get(ctx, "/v1/sized")
+	assert.True(errors.Is(err, ErrResponseTooLarge))
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+
+	res, err := c.Get(ctx, "/v1/chunked")
+	assert.NoError(err)
+	content, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(ErrResponseTooLarge, err)
+	assert.Len(content, 1024)
+
+	unlimited := NewClient("limit-test", server.URL, WithRetryPolicy(testRetryPolicy))
+	res, err = unlimited.Get(ctx, "/v1/chunked")
+	assert.NoError(err)
+	content, err = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NoError(err)
+	assert.Len(content, 2048)
+}
+
+type closeRecorder struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *closeRecorder) Close() error {
+	b.closed = true
+	return nil
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestErrorResponsesAreClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	bodies := make([]*closeRecorder, 0)
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := &closeRecorder{Reader: strings.NewReader(`{"message":"failed"}`)}
+		bodies = append(bodies, body)
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     make(http.Header),
+			Body:       body,
+			Request:    req,
+		}, nil
+	})
+
+	ctx := context.NewBackground("client-id", "sv", "")
+	c := NewClient("close-test", "http://close-test", WithTransport(transport), WithRetryPolicy(testRetryPolicy))
+	_, err := c.Get(ctx, "/v1/failing")
+	assert.Error(err)
+
+	rejecting := func(call *Call, next Invoker) (*http.Response, error) {
+		res, _ := next(call)
+		return res, httputil.BadRequest("rejected")
+	}
+	c = NewClient("close-test", "http://close-test", WithTransport(transport), WithRetryPolicy(testRetryPolicy), WithInterceptors(rejecting))
+	_, err = c.Get(ctx, "/v1/rejected")
+	assert.Error(err)
+
+	assert.True(len(bodies) > 1)
+	for _, body := range bodies {
+		assert.True(body.closed)
+	}
+}
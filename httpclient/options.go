@@ -0,0 +1,214 @@
+package httpclient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/mimir-news/mimir-go/tracing"
+)
+
+// Option configures a client.
+type Option func(*client)
+
+// WithHTTPClient sets the http.Client used to send requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the transport used to send requests, e.g. to
+// configure TLS, proxies or connection pooling. Takes precedence
+// over the transport of a client set by WithHTTPClient.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *client) {
+		c.transport = transport
+	}
+}
+
+// WithHeader adds a header sent with every request. Overrides the
+// default headers set by the client.
+func WithHeader(key, value string) Option {
+	return func(c *client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithWarningThreshold sets the latency above which calls are logged as slow.
+func WithWarningThreshold(threshold time.Duration) Option {
+	return func(c *client) {
+		c.warningThreshold = threshold
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithTimeout sets the time the client waits for a call to complete,
+// including retries and reading the response body. A timeout of zero disables it.
+// Use the Timeout call option to override it for a single call.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *client) {
+		c.timeout = timeout
+	}
+}
+
+// WithCircuitBreaker protects the downstream service with a circuit breaker.
+// Breakers are shared between clients with the same name, the config is
+// only used when the first client with a given name creates the breaker.
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(c *client) {
+		c.breaker = getBreaker(c.name, cfg)
+	}
+}
+
+// WithInterceptors adds interceptors to the client. They run in the order
+// supplied, after the default interceptors, once per attempt.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// WithoutDefaultInterceptors removes the default header, latency warning and
+// metrics interceptors, so that they can be replaced using WithInterceptors.
+func WithoutDefaultInterceptors() Option {
+	return func(c *client) {
+		c.skipDefaults = true
+	}
+}
+
+// WithErrorPolicy sets how error responses from the downstream service are returned to callers.
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(c *client) {
+		c.errorPolicy = policy
+	}
+}
+
+// WithCache caches GET responses in the store. Cached responses are served
+// while fresh according to Cache-Control max-age and revalidated using their
+// ETag and Last-Modified headers when stale. Responses marked no-store or
+// private are never cached.
+func WithCache(store CacheStore) Option {
+	return func(c *client) {
+		c.cache = newResponseCache(c.name, store)
+	}
+}
+
+// WithRateLimit limits the rate of all requests sent by the client.
+func WithRateLimit(limit RateLimit) Option {
+	return func(c *client) {
+		c.limiters = append(c.limiters, newRateLimiter(c.name, "", limit))
+	}
+}
+
+// WithEndpointRateLimit limits the rate of requests to paths matching the pattern,
+// using the syntax of path.Match, e.g. /v1/stocks/*/tweets. Query parameters
+// are not part of the matched path.
+func WithEndpointRateLimit(pattern string, limit RateLimit) Option {
+	return func(c *client) {
+		c.limiters = append(c.limiters, newRateLimiter(c.name, pattern, limit))
+	}
+}
+
+// WithBalancer spreads requests over the endpoints of the balancer instead of
+// sending them to the base url of the client. Each attempt of a call may be
+// sent to a different endpoint. The base url is still used to label metrics.
+func WithBalancer(balancer *Balancer) Option {
+	return func(c *client) {
+		c.balancer = balancer
+	}
+}
+
+// WithSingleflight collapses concurrent identical GET requests into a single
+// request downstream, sharing the buffered response between the callers.
+func WithSingleflight() Option {
+	return func(c *client) {
+		c.singleflight = newSingleflight(c.name)
+	}
+}
+
+// WithBulkhead caps the number of requests the client has in flight. Calls
+// beyond the cap wait in a bounded queue and are rejected with 503 when
+// the queue is full or the queue timeout passes.
+func WithBulkhead(cfg BulkheadConfig) Option {
+	return func(c *client) {
+		c.bulkhead = newBulkhead(c.name, cfg)
+	}
+}
+
+// WithIdempotencyKeys sends a generated Idempotency-Key header with every POST
+// request, unless a key is supplied with the IdempotencyKey call option. The key
+// stays the same across the retries of a call, which makes POST requests retryable.
+func WithIdempotencyKeys() Option {
+	return func(c *client) {
+		c.idempotencyKeys = true
+	}
+}
+
+// WithTokenSource authenticates the calls of the client with tokens of the token
+// source, according to the auth mode.
+func WithTokenSource(source TokenSource, mode AuthMode) Option {
+	return func(c *client) {
+		c.tokenSource = source
+		c.authMode = mode
+	}
+}
+
+// WithSigning signs every request with a HMAC-SHA256 signature, using the current key of
+// the client in the X-ClientID header. Receiving services verify it with httputil.VerifySignatures.
+func WithSigning(keys httputil.KeyStore) Option {
+	return func(c *client) {
+		c.signingKeys = keys
+	}
+}
+
+// WithMaxResponseSize caps the size of downstream response bodies. Larger responses fail with
+// ErrResponseTooLarge. Responses are not capped by default, so that large streams can be read.
+func WithMaxResponseSize(size int64) Option {
+	return func(c *client) {
+		c.maxResponseSize = size
+	}
+}
+
+// WithLeakDetection logs a warning when a response body returned by the client is garbage
+// collected without being closed. Enabled for all clients by setting HTTPCLIENT_DEBUG=true.
+func WithLeakDetection() Option {
+	return func(c *client) {
+		c.leakDetection = true
+	}
+}
+
+// WithDumper logs the requests and responses of the client, with secrets redacted,
+// while the dumper is enabled.
+func WithDumper(dumper *Dumper) Option {
+	return func(c *client) {
+		c.dumper = dumper
+	}
+}
+
+// WithCompression gzips request bodies of at least threshold bytes.
+func WithCompression(threshold int) Option {
+	return func(c *client) {
+		c.compressThreshold = threshold
+	}
+}
+
+// WithTracer records the calls of the client as spans of the tracer instead of the global tracer.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(c *client) {
+		c.tracer = tracer
+	}
+}
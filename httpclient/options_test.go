@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingTransport struct {
+	requests  int
+	transport http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return t.transport.RoundTrip(req)
+}
+
+func TestNewClientOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := &recordingTransport{transport: http.DefaultTransport}
+	c := NewClient("options-test", server.URL,
+		WithHTTPClient(&http.Client{}),
+		WithTransport(transport),
+		WithHeader("X-Api-Key", "secret"),
+		WithHeader("Accept", "application/vnd.mimir+json"),
+		WithUserAgent("mimir-test/1.0"),
+		WithWarningThreshold(time.Second),
+		WithTimeout(time.Second))
+
+	ctx := context.NewBackground("client-id", "en", "auth-token")
+	res, err := c.Get(ctx, "/v1/things")
+	assert.NoError(err)
+	res.Body.Close()
+
+	assert.Equal(1, transport.requests)
+	assert.Equal("secret", header.Get("X-Api-Key"))
+	assert.Equal("application/vnd.mimir+json", header.Get("Accept"))
+	assert.Equal("mimir-test/1.0", header.Get("User-Agent"))
+	assert.Equal("Bearer auth-token", header.Get("Authorization"))
+	assert.Equal("client-id", header.Get("X-ClientID"))
+	assert.Equal(ctx.ID, header.Get(httputil.RequestIDHeader))
+	assert.Equal("en", header.Get(httputil.AcceptLanguage))
+
+	impl := c.(*client)
+	assert.Equal(time.Second, impl.warningThreshold)
+	assert.Equal(time.Second, impl.timeout)
+	assert.Equal(transport, impl.httpClient.Transport)
+}
+
+func TestNewKeepsDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	c := New("defaults-test", "http://localhost", 100*time.Millisecond).(*client)
+	assert.Equal(http.DefaultClient, c.httpClient)
+	assert.Equal(100*time.Millisecond, c.warningThreshold)
+	assert.Equal(DefaultTimeout, c.timeout)
+	assert.Equal(DefaultRetryPolicy.MaxAttempts, c.retryPolicy.MaxAttempts)
+}
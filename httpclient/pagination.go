@@ -0,0 +1,287 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// DefaultMaxPages is the maximum number of pages fetched by a page iterator unless configured.
+const DefaultMaxPages = 100
+
+// ErrTooManyPages returned when a list has more pages than the max page count of an iterator.
+var ErrTooManyPages = errors.New("too many pages")
+
+// Page is a fetched page of a list, passed to a Paging to find the next page.
+type Page struct {
+	// Path is the path and query the page was fetched from.
+	Path string
+	// URL is the full url the page was fetched from, against which links are resolved.
+	URL    *url.URL
+	Header http.Header
+	// Body holds the fields of the page if it is a JSON object, nil if it is a JSON array.
+	Body  map[string]json.RawMessage
+	Items int
+}
+
+// Paging finds the path of the next page of a list, or an empty string after the last page.
+type Paging interface {
+	NextPage(page Page) (string, error)
+}
+
+// LinkPaging follows the rel=next url of the Link header.
+type LinkPaging struct{}
+
+var nextLinkRegexp = regexp.MustCompile(`<([^>]*)>\s*;[^,]*rel="?next"?`)
+
+// NextPage returns the path of the next link, resolved against the url of the page.
+// Links to other hosts or outside of the base url of the client are rejected.
+func (LinkPaging) NextPage(page Page) (string, error) {
+	for _, link := range page.Header["Link"] {
+		match := nextLinkRegexp.FindStringSubmatch(link)
+		if match == nil {
+			continue
+		}
+
+		next, err := url.Parse(match[1])
+		if err != nil {
+			return "", err
+		}
+		return pathOfLink(page, next)
+	}
+
+	return "", nil
+}
+
+// pathOfLink resolves a link against the url of a page and strips the base url
+// the page was fetched from, so that the link can be fetched by the client.
+func pathOfLink(page Page, link *url.URL) (string, error) {
+	if page.URL == nil {
+		return "", fmt.Errorf("cannot resolve link %s without the url of the page", link)
+	}
+
+	current := page.URL
+	resolved := current.ResolveReference(link)
+	if resolved.Scheme != current.Scheme || resolved.Host != current.Host {
+		return "", fmt.Errorf("link to %s://%s points to another host than %s://%s", resolved.Scheme, resolved.Host, current.Scheme, current.Host)
+	}
+
+	pagePath, err := url.Parse(page.Path)
+	if err != nil {
+		return "", err
+	}
+
+	basePath := ""
+	if strings.HasSuffix(current.Path, pagePath.Path) {
+		basePath = strings.TrimSuffix(current.Path, pagePath.Path)
+	}
+	if basePath != "" && !strings.HasPrefix(resolved.Path, basePath+"/") {
+		return "", fmt.Errorf("link %s points outside of the base path %s", resolved.Path, basePath)
+	}
+
+	resolved.Path = strings.TrimPrefix(resolved.Path, basePath)
+	resolved.RawPath = ""
+	return resolved.RequestURI(), nil
+}
+
+// CursorPaging reads the cursor of the next page from a field of the page
+// and sends it as a query parameter. An empty or null cursor ends the list.
+type CursorPaging struct {
+	Field string
+	Param string
+}
+
+// NextPage returns the path with the cursor of the next page.
+func (p CursorPaging) NextPage(page Page) (string, error) {
+	raw, ok := page.Body[p.Field]
+	if !ok {
+		return "", nil
+	}
+
+	var cursor interface{}
+	err := json.Unmarshal(raw, &cursor)
+	if err != nil {
+		return "", err
+	}
+
+	var value string
+	switch c := cursor.(type) {
+	case nil:
+		return "", nil
+	case string:
+		value = c
+	case float64:
+		value = strconv.FormatFloat(c, 'f', -1, 64)
+	default:
+		return "", fmt.Errorf("unsupported cursor type %T of field %s", cursor, p.Field)
+	}
+	if value == "" {
+		return "", nil
+	}
+
+	return withQueryParams(page.Path, map[string]string{p.Param: value})
+}
+
+// OffsetPaging pages by offset and limit query parameters. The list ends with
+// a page of fewer than Limit items. The first page is fetched as is, so its
+// path should carry the limit.
+type OffsetPaging struct {
+	OffsetParam string
+	LimitParam  string
+	Limit       int
+}
+
+// NextPage returns the path with the offset of the next page.
+func (p OffsetPaging) NextPage(page Page) (string, error) {
+	if page.Items == 0 || page.Items < p.Limit {
+		return "", nil
+	}
+
+	current, err := url.Parse(page.Path)
+	if err != nil {
+		return "", err
+	}
+
+	offset, _ := strconv.Atoi(current.Query().Get(p.OffsetParam))
+	return withQueryParams(page.Path, map[string]string{
+		p.OffsetParam: strconv.Itoa(offset + page.Items),
+		p.LimitParam:  strconv.Itoa(p.Limit),
+	})
+}
+
+// PageConfig configures a page iterator.
+type PageConfig struct {
+	Paging Paging
+	// ItemsField is the field of a page holding the items. Empty if pages are JSON arrays.
+	ItemsField string
+	// MaxPages defaults to DefaultMaxPages.
+	MaxPages int
+}
+
+// PageIterator iterates over the items of a list fetched page by page.
+type PageIterator struct {
+	client Client
+	ctx    *context.Context
+	cfg    PageConfig
+	next   string
+	pages  int
+	items  []json.RawMessage
+	err    error
+}
+
+// Paginate creates an iterator over the items of the list starting at path. Pages are fetched as the items are consumed.
+func Paginate(c Client, ctx *context.Context, path string, cfg PageConfig) *PageIterator {
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = DefaultMaxPages
+	}
+
+	return &PageIterator{
+		client: c,
+		ctx:    ctx,
+		cfg:    cfg,
+		next:   path,
+	}
+}
+
+// Next decodes the next item into out. Returns io.EOF after the last item,
+// ErrTooManyPages if the list has more than the max pages and the context
+// error if the context is done.
+func (it *PageIterator) Next(out interface{}) error {
+	if it.err != nil {
+		return it.err
+	}
+
+	for len(it.items) == 0 {
+		if it.next == "" {
+			it.err = io.EOF
+			return it.err
+		}
+		if it.pages >= it.cfg.MaxPages {
+			it.err = ErrTooManyPages
+			return it.err
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return it.err
+		}
+
+		it.err = it.fetch()
+		if it.err != nil {
+			return it.err
+		}
+	}
+
+	item := it.items[0]
+	it.items = it.items[1:]
+	err := json.Unmarshal(item, out)
+	if err != nil {
+		message := fmt.Sprintf("Failed to decode downstream list item. requestId=[%s] page=[%d] type=[%T] err=[%s]", it.ctx.ID, it.pages, out, err)
+		return httputil.BadGateway(message)
+	}
+
+	return nil
+}
+
+// Pages returns the number of pages fetched so far.
+func (it *PageIterator) Pages() int {
+	return it.pages
+}
+
+func (it *PageIterator) fetch() error {
+	path := it.next
+	res, err := it.client.Get(it.ctx, path)
+	if err != nil {
+		return err
+	}
+
+	var raw json.RawMessage
+	err = DecodeJSON(it.ctx, res, &raw)
+	if err != nil {
+		return err
+	}
+	it.pages++
+
+	page := Page{Path: path, Header: res.Header}
+	if res.Request != nil {
+		page.URL = res.Request.URL
+	}
+	if it.cfg.ItemsField == "" {
+		err = json.Unmarshal(raw, &it.items)
+	} else if err = json.Unmarshal(raw, &page.Body); err == nil {
+		if items, ok := page.Body[it.cfg.ItemsField]; ok {
+			err = json.Unmarshal(items, &it.items)
+		}
+	}
+	if err != nil {
+		message := fmt.Sprintf("Failed to decode downstream list page. requestId=[%s] page=[%d] err=[%s]", it.ctx.ID, it.pages, err)
+		return httputil.BadGateway(message)
+	}
+
+	page.Items = len(it.items)
+	it.next, err = it.cfg.Paging.NextPage(page)
+	return err
+}
+
+// withQueryParams sets query parameters of a path.
+func withQueryParams(path string, params map[string]string) (string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.RequestURI(), nil
+}
@@ -0,0 +1,123 @@
+package httpclient
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/stretchr/testify/assert"
+)
+
+type pagedTweet struct {
+	ID int `json:"id"`
+}
+
+func TestPaginate(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		switch strings.TrimPrefix(r.URL.Path, "/prefix") {
+		case "/link":
+			page, _ := strconv.Atoi(query.Get("page"))
+			if page < 2 {
+				w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next", </link?page=0>; rel="first"`, r.Host, r.URL.Path, page+1))
+			}
+			fmt.Fprintf(w, `[{"id":%d},{"id":%d}]`, page*2, page*2+1)
+		case "/relative":
+			page, _ := strconv.Atoi(query.Get("page"))
+			if page < 1 {
+				w.Header().Set("Link", fmt.Sprintf(`<?page=%d>; rel=next`, page+1))
+			}
+			fmt.Fprintf(w, `[{"id":%d}]`, page)
+		case "/foreign":
+			w.Header().Set("Link", `<http://other.host/foreign?page=1>; rel="next"`)
+			w.Write([]byte(`[{"id":0}]`))
+		case "/cursor":
+			switch query.Get("cursor") {
+			case "":
+				w.Write([]byte(`{"tweets":[{"id":0},{"id":1}],"next":"abc"}`))
+			case "abc":
+				w.Write([]byte(`{"tweets":[{"id":2}],"next":null}`))
+			}
+		case "/offset":
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			limit, _ := strconv.Atoi(query.Get("limit"))
+			items := make([]string, 0)
+			for i := offset; i < offset+limit && i < 5; i++ {
+				items = append(items, fmt.Sprintf(`{"id":%d}`, i))
+			}
+			fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("pagination-test", server.URL)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	tests := []struct {
+		path     string
+		cfg      PageConfig
+		expected []int
+		pages    int
+	}{
+		{"/link", PageConfig{Paging: LinkPaging{}}, []int{0, 1, 2, 3, 4, 5}, 3},
+		{"/relative", PageConfig{Paging: LinkPaging{}}, []int{0, 1}, 2},
+		{"/cursor", PageConfig{Paging: CursorPaging{Field: "next", Param: "cursor"}, ItemsField: "tweets"}, []int{0, 1, 2}, 2},
+		{"/offset?limit=2", PageConfig{Paging: OffsetPaging{OffsetParam: "offset", LimitParam: "limit", Limit: 2}, ItemsField: "items"}, []int{0, 1, 2, 3, 4}, 3},
+	}
+
+	for _, test := range tests {
+		it := Paginate(c, ctx, test.path, test.cfg)
+		ids, err := collectTweetIDs(it)
+		assert.Equal(io.EOF, err, test.path)
+		assert.Equal(test.expected, ids, test.path)
+		assert.Equal(test.pages, it.Pages(), test.path)
+	}
+
+	prefixed := NewClient("pagination-test", server.URL+"/prefix")
+	it := Paginate(prefixed, ctx, "/link", PageConfig{Paging: LinkPaging{}})
+	ids, err := collectTweetIDs(it)
+	assert.Equal(io.EOF, err)
+	assert.Equal([]int{0, 1, 2, 3, 4, 5}, ids)
+
+	it = Paginate(c, ctx, "/foreign", PageConfig{Paging: LinkPaging{}})
+	ids, err = collectTweetIDs(it)
+	assert.Error(err)
+	assert.NotEqual(io.EOF, err)
+	assert.Empty(ids)
+
+	it = Paginate(c, ctx, "/link", PageConfig{Paging: LinkPaging{}, MaxPages: 2})
+	ids, err = collectTweetIDs(it)
+	assert.Equal(ErrTooManyPages, err)
+	assert.Equal([]int{0, 1, 2, 3}, ids)
+
+	cancelCtx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	ctx = context.New(cancelCtx, "request-id", "client-id", "sv", "")
+	it = Paginate(c, ctx, "/link", PageConfig{Paging: LinkPaging{}})
+	var tweet pagedTweet
+	assert.NoError(it.Next(&tweet))
+	assert.NoError(it.Next(&tweet))
+	cancel()
+	assert.Equal(stdcontext.Canceled, it.Next(&tweet))
+	assert.Equal(1, it.Pages())
+}
+
+func collectTweetIDs(it *PageIterator) ([]int, error) {
+	ids := make([]int, 0)
+	for {
+		var tweet pagedTweet
+		err := it.Next(&tweet)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, tweet.ID)
+	}
+}
@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// RateLimit configures a token bucket limiting the rate of outgoing requests.
+type RateLimit struct {
+	// Rate is the number of requests allowed per second.
+	Rate float64
+	// Burst is the number of requests that may be sent at once.
+	Burst int
+	// Wait makes requests block until a token is available, bounded by the
+	// request context. When false requests fail fast with a 429 error.
+	Wait bool
+}
+
+type rateLimiter struct {
+	client  string
+	pattern string
+	limit   RateLimit
+	bucket  *tokenBucket
+}
+
+func newRateLimiter(client, pattern string, limit RateLimit) *rateLimiter {
+	return &rateLimiter{
+		client:  client,
+		pattern: pattern,
+		limit:   limit,
+		bucket:  newTokenBucket(limit.Rate, limit.Burst),
+	}
+}
+
+// matches checks if the limiter applies to a call, limiters without a pattern apply to all calls.
+func (l *rateLimiter) matches(call *Call) bool {
+	if l.pattern == "" {
+		return true
+	}
+
+	requestPath := strings.Split(call.Path, "?")[0]
+	ok, err := path.Match(l.pattern, requestPath)
+	return err == nil && ok
+}
+
+// acquire takes a token for the call, waiting for it if the limit allows.
+func (l *rateLimiter) acquire(call *Call) error {
+	if !l.limit.Wait {
+		if l.bucket.take() {
+			return nil
+		}
+		return l.limitedError(call)
+	}
+
+	wait := l.bucket.reserve()
+	rpcRateLimiterWait.WithLabelValues(l.client).Observe(toMilliseconds(wait))
+	if wait == 0 {
+		return nil
+	}
+
+	ctx := call.Request.Context()
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		l.bucket.cancel()
+		return l.limitedError(call)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.bucket.cancel()
+		return ctx.Err()
+	}
+}
+
+func (l *rateLimiter) limitedError(call *Call) error {
+	message := fmt.Sprintf("Rate limit exceeded for downstream service %s. requestId=[%s] path=[%s]", l.client, call.Ctx.ID, stripQueryParameters(call.Path))
+	return httputil.TooManyRequests(message)
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// take takes a token if one is available.
+func (b *tokenBucket) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// reserve takes a token and returns how long the caller must wait before it may be used.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+func (b *tokenBucket) refill() {
+	now := b.now()
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
@@ -0,0 +1,90 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitFailFast(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := NewClient("ratelimit-test", server.URL,
+		WithEndpointRateLimit("/v1/quotes/*", RateLimit{Rate: 0.1, Burst: 1}))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Get(ctx, "/v1/quotes/AAPL?date=today")
+	assert.NoError(err)
+	res.Body.Close()
+
+	_, err = c.Get(ctx, "/v1/quotes/MSFT")
+	var httpErr *httputil.Error
+	assert.True(errors.As(err, &httpErr))
+	assert.Equal(http.StatusTooManyRequests, httpErr.StatusCode)
+
+	res, err = c.Get(ctx, "/v1/stocks/AAPL")
+	assert.NoError(err, "paths not matching the pattern should not be limited")
+	res.Body.Close()
+}
+
+func TestRateLimitWait(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := NewClient("ratelimit-test", server.URL, WithRateLimit(RateLimit{Rate: 20, Burst: 1, Wait: true}))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ctx, "/v1/things")
+		assert.NoError(err)
+		res.Body.Close()
+	}
+	assert.True(time.Since(start) >= 90*time.Millisecond, "requests should wait for tokens")
+
+	c = NewClient("ratelimit-test", server.URL, WithRateLimit(RateLimit{Rate: 0.1, Burst: 1, Wait: true}))
+	res, err := c.Get(ctx, "/v1/things")
+	assert.NoError(err)
+	res.Body.Close()
+
+	_, err = c.Get(WithCallOptions(ctx, Timeout(50*time.Millisecond)), "/v1/things")
+	var httpErr *httputil.Error
+	assert.True(errors.As(err, &httpErr))
+	assert.Equal(http.StatusTooManyRequests, httpErr.StatusCode, "wait longer than the deadline should fail fast")
+}
+
+func TestTokenBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	b := newTokenBucket(2, 2)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	assert.True(b.take())
+	assert.True(b.take())
+	assert.False(b.take())
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(b.take())
+	assert.False(b.take())
+
+	assert.Equal(500*time.Millisecond, b.reserve())
+	b.cancel()
+	assert.Equal(500*time.Millisecond, b.reserve())
+}
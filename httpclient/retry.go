@@ -0,0 +1,283 @@
+package httpclient
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// RetryPolicy describes if and how failed downstream calls are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the wait time before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait time between attempts. A Retry-After header asking
+	// for a longer wait than MaxBackoff ends the retries.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff grows by after each attempt.
+	Multiplier float64
+	// Jitter is the fraction (0-1) of the backoff that is randomized.
+	Jitter float64
+	// Methods are the http methods that may be retried. Requests carrying
+	// an Idempotency-Key header may be retried regardless of their method.
+	Methods []string
+	// StatusCodes are the downstream response codes that are retried.
+	StatusCodes []int
+}
+
+// DefaultRetryPolicy retries idempotent requests on connection errors,
+// throttling and unavailable downstream services.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	Methods:        []string{http.MethodGet, http.MethodPut, http.MethodDelete},
+	StatusCodes: []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// NoRetries makes exactly one attempt per request.
+var NoRetries = RetryPolicy{MaxAttempts: 1}
+
+func (p RetryPolicy) allowsMethod(method string) bool {
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) retryableStatus(statusCode int) bool {
+	for _, code := range p.StatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable checks if the outcome of an attempt is worth retrying.
+func (p RetryPolicy) retryable(req *http.Request, res *http.Response, err error) bool {
+	if !p.allowsMethod(req.Method) && req.Header.Get(httputil.IdempotencyKeyHeader) == "" {
+		return false
+	}
+
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	return p.retryableStatus(res.StatusCode)
+}
+
+// backoff calculates the wait time before the next attempt, returns false
+// if the downstream asked for a longer wait than the policy allows.
+func (p RetryPolicy) backoff(attempt int, res *http.Response) (time.Duration, bool) {
+	if wait, ok := parseRetryAfter(res); ok {
+		return wait, wait <= p.MaxBackoff
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	wait := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+
+	wait += wait * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(wait), true
+}
+
+// parseRetryAfter parses the Retry-After header of a response,
+// either expressed in seconds or as a http date.
+func parseRetryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	wait := time.Until(date)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// retry sends a call downstream, making new attempts according to the retry policy.
+func (c *client) retry(call *Call) (*http.Response, error) {
+	policy := c.retryPolicy
+	req := call.Request
+	for attempt := 1; ; attempt++ {
+		attemptCall := &Call{
+			Ctx:     call.Ctx,
+			Client:  call.Client,
+			BaseURL: call.BaseURL,
+			Method:  call.Method,
+			Path:    call.Path,
+			Route:   call.Route,
+			Attempt: attempt,
+			Request: req,
+		}
+
+		res, err := c.attempt(attemptCall)
+		if httpErr, ok := err.(*httputil.Error); ok {
+			drainAndClose(res)
+			return nil, httpErr
+		}
+
+		if !policy.retryable(req, res, err) {
+			return res, err
+		}
+
+		if attempt >= policy.MaxAttempts {
+			if policy.MaxAttempts > 1 {
+				log.Warnw("Retries exhausted for downstream call",
+					"client", c.name,
+					"method", req.Method,
+					"path", stripQueryParameters(call.Path),
+					"attempts", attempt,
+					"requestId", call.Ctx.ID,
+					"status", statusCode(res),
+					"error", err)
+			}
+			return res, err
+		}
+
+		wait, ok := policy.backoff(attempt, res)
+		if !ok {
+			return res, err
+		}
+
+		drainAndClose(res)
+		if err = sleep(req, wait); err != nil {
+			return nil, err
+		}
+
+		req, err = rewindRequest(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends a single call through the rate limiters, circuit breaker and
+// load balancer, if configured, and the interceptor chain.
+func (c *client) attempt(call *Call) (*http.Response, error) {
+	for _, limiter := range c.limiters {
+		if !limiter.matches(call) {
+			continue
+		}
+		if err := limiter.acquire(call); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.breaker != nil {
+		if err := c.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	var upstream *endpoint
+	if c.balancer != nil {
+		upstream = c.balancer.pick()
+		req, err := withBaseURL(call.Request, upstream.baseURL, call.Path)
+		if err != nil {
+			// Recording the failure returns a half-open probe slot taken by allow.
+			if c.breaker != nil {
+				c.breaker.record(true)
+			}
+			c.balancer.done(upstream, false)
+			return nil, err
+		}
+		call.Request = req
+	}
+
+	res, err := c.invoke(call)
+	failed := breakerFailure(call, res, err)
+	if c.breaker != nil {
+		c.breaker.record(failed)
+	}
+	if upstream != nil {
+		c.balancer.done(upstream, failed)
+	}
+
+	return res, err
+}
+
+// sleep waits before the next attempt, returns early if the request context is done.
+func sleep(req *http.Request, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// rewindRequest creates a copy of a request with a fresh body so that it can be resent.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.WithContext(req.Context())
+	retry.Body = body
+	return retry, nil
+}
+
+// drainAndClose reads the remaining response body so that the underlying
+// connection can be reused and then closes it.
+func drainAndClose(res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+	res.Body.Close()
+}
@@ -0,0 +1,132 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+	Multiplier:     2,
+	Jitter:         0.2,
+	Methods:        DefaultRetryPolicy.Methods,
+	StatusCodes:    DefaultRetryPolicy.StatusCodes,
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := New("retry-test", server.URL, time.Second, WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	res, err := c.Get(ctx, "/v1/things")
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetryExhausted(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := New("retry-test", server.URL, time.Second, WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	_, err := c.Put(ctx, "/v1/things", map[string]string{"name": "thing"})
+	assert.Error(err)
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRetryOnlyIdempotentMethods(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := New("retry-test", server.URL, time.Second, WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	_, err := c.Post(ctx, "/v1/things", map[string]string{"name": "thing"})
+	assert.Error(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	c = New("retry-test", server.URL, time.Second, WithRetryPolicy(NoRetries))
+	_, err = c.Get(ctx, "/v1/things")
+	assert.Error(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryNotOnClientErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := New("retry-test", server.URL, time.Second, WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	_, err := c.Get(ctx, "/v1/things")
+	assert.Error(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	policy := testRetryPolicy
+
+	res := &http.Response{Header: http.Header{}}
+	res.Header.Set("Retry-After", "0")
+	wait, ok := policy.backoff(1, res)
+	assert.True(ok)
+	assert.Equal(time.Duration(0), wait)
+
+	res.Header.Set("Retry-After", "120")
+	wait, ok = policy.backoff(1, res)
+	assert.False(ok)
+	assert.Equal(120*time.Second, wait)
+
+	res.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	wait, ok = policy.backoff(1, res)
+	assert.True(ok)
+	assert.Equal(time.Duration(0), wait)
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		wait, ok = policy.backoff(attempt, nil)
+		assert.True(ok)
+		assert.True(wait <= 12*time.Millisecond, "backoff should be capped at MaxBackoff plus jitter")
+	}
+}
@@ -0,0 +1,47 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// expandPath replaces the :name segments of a route template, e.g. /v1/stocks/:symbol/tweets,
+// with the escaped values of the path parameters. A query string is kept as is.
+func expandPath(template string, params map[string]string) (string, error) {
+	routeAndQuery := strings.SplitN(template, "?", 2)
+	segments := strings.Split(routeAndQuery[0], "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+
+		name := segment[1:]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("missing path parameter %s in route %s", name, routeAndQuery[0])
+		}
+		if value == "" || value == "." || value == ".." {
+			return "", fmt.Errorf("invalid value [%s] of path parameter %s in route %s", value, name, routeAndQuery[0])
+		}
+
+		segments[i] = url.PathEscape(value)
+	}
+
+	path := strings.Join(segments, "/")
+	if len(routeAndQuery) == 2 {
+		path += "?" + routeAndQuery[1]
+	}
+
+	return path, nil
+}
+
+// routeOf creates the route of a call used to label metrics. Route templates are used as is,
+// otherwise the query and any UUIDs are stripped from the path.
+func routeOf(path string, params map[string]string) string {
+	if params != nil {
+		return strings.Split(path, "?")[0]
+	}
+
+	return stripQueryAndUUIDs(path)
+}
@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandPath(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := expandPath("/v1/stocks/:symbol/tweets/:id?limit=10", map[string]string{
+		"symbol": "BRK/B",
+		"id":     "1183749302 ?",
+	})
+	assert.NoError(err)
+	assert.Equal("/v1/stocks/BRK%2FB/tweets/1183749302%20%3F?limit=10", path)
+
+	_, err = expandPath("/v1/stocks/:symbol", map[string]string{})
+	assert.Error(err)
+
+	_, err = expandPath("/v1/stocks/:symbol/tweets", map[string]string{"symbol": ".."})
+	assert.Error(err)
+
+	assert.Equal("/v1/stocks/:symbol", routeOf("/v1/stocks/:symbol?limit=10", map[string]string{}))
+	assert.Equal("/v1/tweets/:id", routeOf("/v1/tweets/3c8a0e58-5b3f-4e2a-9a55-3b8d2b0c0d3e?limit=10", nil))
+}
+
+func TestPathParams(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/v1/stocks/BRK%2FB/tweets", r.URL.EscapedPath())
+		assert.Equal("10", r.URL.Query().Get("limit"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c := NewClient("route-test", server.URL)
+	ctx := context.NewBackground("client-id", "sv", "")
+	ctx = WithCallOptions(ctx, PathParams(map[string]string{"symbol": "BRK/B"}))
+
+	_, err := c.Get(ctx, "/v1/stocks/:symbol/tweets?limit=10")
+	assert.NoError(err)
+
+	upstream := strings.TrimPrefix(server.URL, "http://")
+	requests := rpcsTotal.WithLabelValues("route-test", server.URL+"/v1/stocks/:symbol/tweets", upstream, http.MethodGet, "204", "1")
+	assert.Equal(float64(1), testutil.ToFloat64(requests))
+
+	_, err = c.Get(ctx, "/v1/stocks/:symbol/tweets/:id")
+	assert.Error(err)
+}
@@ -0,0 +1,81 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/mimir-news/mimir-go/id"
+)
+
+// sign signs every attempt with the current key of the client in the X-ClientID header,
+// using a fresh timestamp and nonce. Must run last so that the signature covers the request as sent.
+func (c *client) sign(call *Call, next Invoker) (*http.Response, error) {
+	req := call.Request
+	clientID := req.Header.Get(httputil.ClientIDHeader)
+	if clientID == "" {
+		clientID = call.Ctx.ClientID
+		req.Header.Set(httputil.ClientIDHeader, clientID)
+	}
+
+	key, ok := c.signingKeys.CurrentKey(clientID)
+	if !ok {
+		log.Errorw("No signing key for client", "client", c.name, "clientId", clientID, "requestId", call.Ctx.ID)
+		message := fmt.Sprintf("Failed to sign request to downstream service %s. requestId=[%s]", c.name, call.Ctx.ID)
+		return nil, httputil.InternalServerError(message)
+	}
+
+	body, err := signedBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := id.New()
+	signature := httputil.Signature(key.Secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body, clientID)
+
+	req.Header.Set(httputil.SignatureKeyIDHeader, key.ID)
+	req.Header.Set(httputil.SignatureTimestampHeader, timestamp)
+	req.Header.Set(httputil.SignatureNonceHeader, nonce)
+	req.Header.Set(httputil.SignatureHeader, signature)
+	return next(call)
+}
+
+// signedBody reads the body of a request without consuming it. Streamed bodies are buffered in memory.
+// Compressed bodies are signed decompressed, since receiving services decompress them before verifying.
+func signedBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	content, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeContent(req.Header, content)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return ioutil.ReadAll(body)
+	}
+
+	content, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(content))
+	return content, nil
+}
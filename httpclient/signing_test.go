@@ -0,0 +1,66 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mimir-news/mimir-go/httpclient/httpclienttest"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigning(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	keys := httputil.NewMemoryKeyStore()
+	keys.AddKey(httpclienttest.ClientID, httputil.SigningKey{ID: "key-1", Secret: []byte("secret")})
+
+	var attempts int32
+	r := httputil.NewRouter(func() error { return nil })
+	r.Use(httputil.VerifySignatures(httputil.VerifyConfig{
+		Keys:   keys,
+		Nonces: httputil.NewMemoryNonceStore(),
+	}))
+	r.POST("/v1/orders", func(c *gin.Context) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			c.Error(httputil.ServiceUnavailable("Try again"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	policy := DefaultRetryPolicy
+	policy.InitialBackoff = 0
+	policy.Jitter = 0
+	client := NewClient("signed", server.URL, WithSigning(keys), WithIdempotencyKeys(), WithRetryPolicy(policy))
+
+	ctx := httpclienttest.NewContext()
+	res, err := client.Post(ctx, "/v1/orders?dryRun=true", map[string]string{"symbol": "AAPL"})
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal(int32(2), atomic.LoadInt32(&attempts))
+	res.Body.Close()
+
+	compressed := NewClient("signed-compressed", server.URL, WithSigning(keys), WithCompression(1), WithRetryPolicy(policy))
+	atomic.StoreInt32(&attempts, 1)
+	res, err = compressed.Post(ctx, "/v1/orders", map[string]string{"symbol": "AAPL"})
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	unsigned := NewClient("unsigned", server.URL)
+	_, err = unsigned.Post(ctx, "/v1/orders", map[string]string{"symbol": "AAPL"})
+	assert.Error(err)
+
+	other := NewClient("other", server.URL, WithSigning(httputil.NewMemoryKeyStore()))
+	_, err = other.Post(ctx, "/v1/orders", map[string]string{"symbol": "AAPL"})
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusInternalServerError, httpErr.StatusCode)
+}
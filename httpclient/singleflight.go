@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// flight is a GET request in flight whose response is shared by identical concurrent calls.
+type flight struct {
+	done chan struct{}
+	res  *CachedResponse
+	err  error
+	// abandoned is set if the request failed because the call that sent it was cancelled or timed out.
+	abandoned bool
+}
+
+type singleflight struct {
+	client string
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+func newSingleflight(client string) *singleflight {
+	return &singleflight{
+		client:  client,
+		flights: make(map[string]*flight),
+	}
+}
+
+// intercept collapses concurrent identical GET requests into one request downstream.
+// The response is buffered and every call receives its own copy. Calls waiting for
+// the request are bound by their own context. If the request fails because the call
+// that sent it was cancelled or timed out, the waiting calls send the request again.
+func (sf *singleflight) intercept(call *Call, next Invoker) (*http.Response, error) {
+	req := call.Request
+	if req.Method != http.MethodGet {
+		return next(call)
+	}
+
+	key := cacheKey(call)
+	sf.mu.Lock()
+	if f, ok := sf.flights[key]; ok {
+		sf.mu.Unlock()
+		rpcDeduplicatedTotal.WithLabelValues(sf.client).Inc()
+		if err := f.wait(req); err != nil {
+			return nil, err
+		}
+		if f.abandoned {
+			return sf.intercept(call, next)
+		}
+		return f.result(req)
+	}
+
+	f := &flight{done: make(chan struct{})}
+	sf.flights[key] = f
+	sf.mu.Unlock()
+
+	f.res, f.err = bufferResponse(next(call))
+	f.abandoned = f.err != nil && req.Context().Err() != nil
+
+	sf.mu.Lock()
+	delete(sf.flights, key)
+	sf.mu.Unlock()
+	close(f.done)
+
+	return f.result(req)
+}
+
+// wait waits for the request to complete, returns early if the context of the waiting call is done.
+func (f *flight) wait(req *http.Request) error {
+	select {
+	case <-f.done:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+func (f *flight) result(req *http.Request) (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.res.response(req), nil
+}
+
+// bufferResponse reads and closes the body of a response so that it can be shared.
+func bufferResponse(res *http.Response, err error) (*CachedResponse, error) {
+	if err != nil {
+		if res != nil {
+			drainAndClose(res)
+		}
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CachedResponse{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	stdcontext "context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingleflight(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"symbol":"XYZ"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("singleflight-test", server.URL, WithSingleflight())
+	ctx := context.NewBackground("client-id", "sv", "")
+	deduplicated := rpcDeduplicatedTotal.WithLabelValues("singleflight-test")
+	before := testutil.ToFloat64(deduplicated)
+
+	callers := 10
+	bodies := make([]string, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := c.Get(ctx, "/v1/stocks/XYZ")
+			assert.NoError(err)
+			body, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			bodies[i] = string(body)
+		}(i)
+	}
+
+	for deadline := time.Now().Add(time.Second); testutil.ToFloat64(deduplicated)-before < float64(callers-1); {
+		if time.Now().After(deadline) {
+			t.Fatal("Calls were not deduplicated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+	for _, body := range bodies {
+		assert.Equal(`{"symbol":"XYZ"}`, body)
+	}
+
+	_, err := c.Get(context.NewBackground("client-id", "en", ""), "/v1/stocks/XYZ")
+	assert.NoError(err)
+	assert.Equal(int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestSingleflightCancelledLeader(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"symbol":"XYZ"}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := NewClient("singleflight-cancel-test", server.URL, WithSingleflight(), WithRetryPolicy(NoRetries))
+	deduplicated := rpcDeduplicatedTotal.WithLabelValues("singleflight-cancel-test")
+	before := testutil.ToFloat64(deduplicated)
+
+	leaderCtx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := c.Get(context.New(leaderCtx, "leader", "client-id", "sv", ""), "/v1/stocks/XYZ")
+		leaderErr <- err
+	}()
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&calls) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Leader did not send the request")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	followerBody := make(chan string)
+	go func() {
+		res, err := c.Get(context.NewBackground("client-id", "sv", ""), "/v1/stocks/XYZ")
+		assert.NoError(err)
+		if err != nil {
+			followerBody <- ""
+			return
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		followerBody <- string(body)
+	}()
+	for deadline := time.Now().Add(time.Second); testutil.ToFloat64(deduplicated) == before; {
+		if time.Now().After(deadline) {
+			t.Fatal("Follower was not deduplicated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	assert.Error(<-leaderErr)
+	assert.Equal(`{"symbol":"XYZ"}`, <-followerBody)
+	assert.Equal(int32(2), atomic.LoadInt32(&calls))
+}
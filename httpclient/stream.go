@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+// JSONStream decodes the items of a streamed response one at a time, without
+// buffering the whole response. Both newline delimited JSON and JSON arrays are supported.
+type JSONStream struct {
+	ctx     *context.Context
+	res     *http.Response
+	reader  *bufio.Reader
+	decoder *json.Decoder
+	array   bool
+	started bool
+	items   int
+	err     error
+}
+
+// GetJSONStream performs a GET request and returns a stream of the items in the response.
+// The timeout of the call only covers receiving the response headers, see StreamResponse.
+func GetJSONStream(c Client, ctx *context.Context, path string) (*JSONStream, error) {
+	res, err := c.Get(WithCallOptions(ctx, StreamResponse()), path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONStream(ctx, res)
+}
+
+// NewJSONStream creates a stream of the items in a response. The stream must be closed.
+func NewJSONStream(ctx *context.Context, res *http.Response) (*JSONStream, error) {
+	contentType := res.Header.Get("Content-Type")
+	if !isJSONStream(contentType) {
+		drainAndClose(res)
+		message := fmt.Sprintf("Unexpected Content-Type in downstream stream. requestId=[%s] status=[%d] contentType=[%s]", ctx.ID, res.StatusCode, contentType)
+		return nil, httputil.BadGateway(message)
+	}
+
+	reader := bufio.NewReader(res.Body)
+	return &JSONStream{
+		ctx:     ctx,
+		res:     res,
+		reader:  reader,
+		decoder: json.NewDecoder(reader),
+	}, nil
+}
+
+// Next decodes the next item of the stream into out. Returns io.EOF when the stream has ended.
+func (s *JSONStream) Next(out interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	if !s.started {
+		s.started = true
+		if err := s.start(); err != nil {
+			return s.fail(err)
+		}
+	}
+
+	if s.array && !s.decoder.More() {
+		if _, err := s.decoder.Token(); err != nil {
+			return s.fail(err)
+		}
+		s.err = io.EOF
+		return s.err
+	}
+
+	err := s.decoder.Decode(out)
+	if err == io.EOF && !s.array {
+		s.err = io.EOF
+		return s.err
+	}
+	if err != nil {
+		return s.fail(err)
+	}
+
+	s.items++
+	return nil
+}
+
+// Close closes the response body.
+func (s *JSONStream) Close() error {
+	return s.res.Body.Close()
+}
+
+// start checks if the stream is a JSON array and if so consumes the opening bracket.
+func (s *JSONStream) start() error {
+	for {
+		b, err := s.reader.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			s.reader.ReadByte()
+			continue
+		case '[':
+			s.array = true
+			_, err = s.decoder.Token()
+			return err
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *JSONStream) fail(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	message := fmt.Sprintf("Failed to decode downstream stream. requestId=[%s] status=[%d] item=[%d] err=[%s]", s.ctx.ID, s.res.StatusCode, s.items, err)
+	s.err = httputil.BadGateway(message)
+	return s.err
+}
+
+// isJSONStream checks if a Content-Type header describes a JSON array or newline delimited JSON.
+func isJSONStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return isJSON(contentType) || mediaType == "application/x-ndjson"
+}
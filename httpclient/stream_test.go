@@ -0,0 +1,155 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type streamedTweet struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+func TestJSONStream(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/tweets.ndjson":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Write([]byte("{\"id\":1,\"text\":\"first\"}\n{\"id\":2,\"text\":\"second\"}\n"))
+		case "/v1/tweets":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(` [{"id":1,"text":"first"}, {"id":2,"text":"second"}]`))
+		case "/v1/tweets/empty":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[]`))
+		case "/v1/tweets/broken":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"id":1,"text":"first"},{"id":2,"te`))
+		case "/v1/tweets/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("tweets"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("stream-test", server.URL)
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	for _, path := range []string{"/v1/tweets.ndjson", "/v1/tweets"} {
+		stream, err := GetJSONStream(c, ctx, path)
+		assert.NoError(err)
+
+		tweets := make([]streamedTweet, 0)
+		for {
+			var tweet streamedTweet
+			err = stream.Next(&tweet)
+			if err != nil {
+				break
+			}
+			tweets = append(tweets, tweet)
+		}
+		assert.Equal(io.EOF, err, path)
+		assert.Equal([]streamedTweet{{ID: 1, Text: "first"}, {ID: 2, Text: "second"}}, tweets, path)
+		assert.NoError(stream.Close())
+	}
+
+	stream, err := GetJSONStream(c, ctx, "/v1/tweets/empty")
+	assert.NoError(err)
+	var tweet streamedTweet
+	assert.Equal(io.EOF, stream.Next(&tweet))
+	stream.Close()
+
+	stream, err = GetJSONStream(c, ctx, "/v1/tweets/broken")
+	assert.NoError(err)
+	assert.NoError(stream.Next(&tweet))
+	err = stream.Next(&tweet)
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+	assert.Contains(httpErr.Message, ctx.ID)
+	assert.Contains(httpErr.Message, "item=[1]")
+	assert.Equal(err, stream.Next(&tweet))
+	stream.Close()
+
+	_, err = GetJSONStream(c, ctx, "/v1/tweets/text")
+	httpErr, ok = err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, httpErr.StatusCode)
+	assert.Contains(httpErr.Message, ctx.ID)
+}
+
+func TestStreamBody(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal("text/csv", r.Header.Get("Content-Type"))
+		assert.Equal("symbol,name\nAAPL,Apple\n", string(body))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient("stream-test", server.URL, WithRetryPolicy(testRetryPolicy))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	body := Stream(ioutil.NopCloser(strings.NewReader("symbol,name\nAAPL,Apple\n")), "text/csv")
+	_, err := c.Put(ctx, "/v1/stocks/import", body)
+	assert.Error(err)
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestJSONStreamTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "{\"id\":%d}\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("stream-timeout-test", server.URL, WithTimeout(50*time.Millisecond), WithRetryPolicy(NoRetries))
+	ctx := context.NewBackground("client-id", "sv", "")
+
+	stream, err := GetJSONStream(c, ctx, "/v1/export")
+	assert.NoError(err)
+	defer stream.Close()
+
+	items := 0
+	for {
+		var tweet streamedTweet
+		err = stream.Next(&tweet)
+		if err != nil {
+			break
+		}
+		items++
+	}
+	assert.Equal(io.EOF, err)
+	assert.Equal(5, items)
+
+	_, err = GetJSONStream(c, ctx, "/v1/slow")
+	httpErr, ok := err.(*httputil.Error)
+	assert.True(ok)
+	assert.Equal(http.StatusGatewayTimeout, httpErr.StatusCode)
+}
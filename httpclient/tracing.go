@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/mimir-news/mimir-go/tracing"
+)
+
+// startSpan starts a client span of a call, continuing the trace carried by the context.
+func (c *client) startSpan(ctx *context.Context, method, route string) *tracing.Span {
+	tracer := c.tracer
+	if tracer == nil {
+		tracer = tracing.GlobalTracer()
+	}
+
+	var parent tracing.SpanContext
+	if span := tracing.SpanFromContext(ctx); span != nil {
+		parent = span.Context()
+	}
+
+	span := tracer.StartSpan(method+" "+route, tracing.ClientSpan, parent)
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.route", route)
+	span.SetAttribute("peer.service", c.name)
+	span.SetAttribute("request.id", ctx.ID)
+	return span
+}
+
+// errorStatus returns the status of an error returned by a call.
+func errorStatus(err error) int {
+	if httpErr, ok := err.(*httputil.Error); ok {
+		return httpErr.StatusCode
+	}
+
+	return http.StatusBadGateway
+}
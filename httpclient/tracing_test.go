@@ -0,0 +1,90 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mimir-news/mimir-go/context"
+	"github.com/mimir-news/mimir-go/httpclient/httpclienttest"
+	"github.com/mimir-news/mimir-go/httputil"
+	"github.com/mimir-news/mimir-go/tracing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracePropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	exporter := tracing.NewInMemoryExporter()
+	tracing.SetGlobalTracer(tracing.NewTracer(exporter))
+	defer tracing.SetGlobalTracer(tracing.NewTracer(nil))
+
+	gin.SetMode(gin.TestMode)
+	downstream := httputil.NewRouter(func() error { return nil })
+	downstream.GET("/v1/prices/:symbol", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	downstreamServer := httptest.NewServer(downstream)
+	defer downstreamServer.Close()
+
+	prices := NewClient("price-service", downstreamServer.URL)
+	upstream := httputil.NewRouter(func() error { return nil })
+	upstream.GET("/v1/stocks/:symbol", func(c *gin.Context) {
+		ctx := context.New(c.Request.Context(), httputil.GetRequestID(c), "client-id", "sv", "")
+		ctx = WithCallOptions(ctx, PathParams(map[string]string{"symbol": c.Param("symbol")}))
+		_, err := prices.Get(ctx, "/v1/prices/:symbol")
+		assert.NoError(err)
+		httputil.SendOK(c)
+	})
+	upstreamServer := httptest.NewServer(upstream)
+	defer upstreamServer.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, upstreamServer.URL+"/v1/stocks/AAPL", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	res.Body.Close()
+
+	spans := exporter.Spans()
+	assert.Len(spans, 3)
+	if len(spans) != 3 {
+		return
+	}
+
+	downstreamSpan, clientSpan, upstreamSpan := spans[0], spans[1], spans[2]
+	for _, span := range spans {
+		assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID.String())
+	}
+
+	assert.Equal(tracing.ServerSpan, upstreamSpan.Kind)
+	assert.Equal("00f067aa0ba902b7", upstreamSpan.ParentSpanID.String())
+	assert.Equal(http.StatusOK, upstreamSpan.StatusCode)
+
+	assert.Equal(tracing.ClientSpan, clientSpan.Kind)
+	assert.Equal("GET /v1/prices/:symbol", clientSpan.Name)
+	assert.Equal(upstreamSpan.SpanContext.SpanID, clientSpan.ParentSpanID)
+	assert.Equal(http.StatusNoContent, clientSpan.StatusCode)
+
+	assert.Equal(tracing.ServerSpan, downstreamSpan.Kind)
+	assert.Equal(clientSpan.SpanContext.SpanID, downstreamSpan.ParentSpanID)
+	assert.True(clientSpan.Duration() >= downstreamSpan.Duration())
+}
+
+func TestSpanFinishedWhenRequestFails(t *testing.T) {
+	assert := assert.New(t)
+
+	exporter := tracing.NewInMemoryExporter()
+	client := NewClient("unencodable", "http://localhost", WithTracer(tracing.NewTracer(exporter)))
+
+	_, err := client.Post(httpclienttest.NewContext(), "/v1/orders", map[string]interface{}{"symbol": make(chan int)})
+	assert.Error(err)
+
+	spans := exporter.Spans()
+	assert.Len(spans, 1)
+	if len(spans) != 1 {
+		return
+	}
+	assert.Equal("POST /v1/orders", spans[0].Name)
+	assert.Equal(errorStatus(err), spans[0].StatusCode)
+}
@@ -0,0 +1,43 @@
+package httputil
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DecompressRequests transparently decompresses gzip encoded request bodies, as
+// sent by httpclient.WithCompression, so that handlers read the plain body.
+func DecompressRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || !strings.EqualFold(c.GetHeader("Content-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+
+		reader, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			abortWithError(c, BadRequest("Invalid gzip encoded request body"))
+			return
+		}
+
+		c.Request.Body = &gzipBody{Reader: reader, body: c.Request.Body}
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// gzipBody decompresses a request body and closes the underlying body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,44 @@
+package httputil_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+func TestDecompressRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := httputil.NewRouter(func() error { return nil })
+	r.POST("/v1/tweets", func(c *gin.Context) {
+		body, _ := ioutil.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, c.GetHeader("Content-Encoding")+string(body))
+	})
+
+	compressed := new(bytes.Buffer)
+	writer := gzip.NewWriter(compressed)
+	writer.Write([]byte(`{"text":"hello"}`))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tweets", compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"text":"hello"}` {
+		t.Errorf("Expected decompressed body, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/tweets", strings.NewReader(`{"text":"hello"}`))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid gzip body, got %d", rec.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package httputil
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -34,12 +35,18 @@ type Error struct {
 	ID         string `json:"id,omitempty"`
 	Message    string `json:"message,omitempty"`
 	StatusCode int    `json:"status,omitempty"`
+	Err        error  `json:"-"`
 }
 
 func (err *Error) Error() string {
 	return fmt.Sprintf("Error(id=%s, statusCode=%d message=[%s])", err.ID, err.StatusCode, err.Message)
 }
 
+// Unwrap returns the underlying cause of the error, nil if not present.
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
 // NewError creates a new error.
 func NewError(message string, status int) *Error {
 	errMsg := message
@@ -74,6 +81,11 @@ func NotFound(message string) *Error {
 	return NewError(message, http.StatusNotFound)
 }
 
+// TooManyRequests creates a new too many requests (429) error.
+func TooManyRequests(message string) *Error {
+	return NewError(message, http.StatusTooManyRequests)
+}
+
 // InternalServerError creates a new internal server error (500).
 func InternalServerError(message string) *Error {
 	return NewError(message, http.StatusInternalServerError)
@@ -84,6 +96,16 @@ func BadGateway(message string) *Error {
 	return NewError(message, http.StatusBadGateway)
 }
 
+// ServiceUnavailable creates a new service unavailable (503) error.
+func ServiceUnavailable(message string) *Error {
+	return NewError(message, http.StatusServiceUnavailable)
+}
+
+// GatewayTimeout creates a new gateway timeout (504) error.
+func GatewayTimeout(message string) *Error {
+	return NewError(message, http.StatusGatewayTimeout)
+}
+
 // ErrorResponse error response annotated with request context.
 type ErrorResponse struct {
 	ErrorID    string `json:"errorId,omitempty"`
@@ -96,13 +118,8 @@ type ErrorResponse struct {
 // NewErrorResponse creates a new error response based on an error an gin context.
 func NewErrorResponse(c *gin.Context, err error) ErrorResponse {
 	var httpError *Error
-	switch err.(type) {
-	case *Error:
-		httpError = err.(*Error)
-		break
-	default:
+	if !errors.As(err, &httpError) {
 		httpError = InternalServerError(err.Error())
-		break
 	}
 
 	return ErrorResponse{
@@ -17,9 +17,11 @@ func NewRouter(healthCheck HealthFunc) *gin.Engine {
 		gin.Recovery(),
 		Metrics(),
 		RequestID(),
+		Tracing(),
 		Locale(),
 		Logger(),
-		HandleErrors())
+		HandleErrors(),
+		DecompressRequests())
 
 	r.GET("/health", checkHealth(healthCheck))
 	r.GET(metricsPath, prometheusHandler())
@@ -0,0 +1,253 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotentReplayedHeader is set on responses replayed for a duplicate request.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// Idempotency store errors.
+var (
+	ErrIdempotencyKeyInUse    = errors.New("idempotency key in use by a request in progress")
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key used for a different request")
+)
+
+// StoredResponse is the response of a request kept for an idempotency key.
+type StoredResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// IdempotencyStore keeps the responses of requests by client id and idempotency key.
+type IdempotencyStore interface {
+	// Reserve claims a key for a request with the fingerprint. Returns the stored
+	// response if the request has completed, nil if the key was claimed by the caller,
+	// ErrIdempotencyKeyInUse if the request is in progress and
+	// ErrIdempotencyKeyMismatch if the key was used for another request.
+	Reserve(clientID, key, fingerprint string) (*StoredResponse, error)
+	// Save stores the response of a reserved key.
+	Save(clientID, key string, res StoredResponse) error
+	// Release removes a reserved key, so that the request can be retried.
+	Release(clientID, key string) error
+}
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to retry. The
+// response of the first request with a key is stored per client id and replayed on
+// duplicates. Failed requests, reported with c.Error or a 5xx status, are not stored.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || isSafeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		clientID := c.GetHeader(ClientIDHeader)
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			abortWithError(c, BadRequest("Failed to read request body"))
+			return
+		}
+
+		stored, err := store.Reserve(clientID, key, fingerprint)
+		switch err {
+		case nil:
+		case ErrIdempotencyKeyInUse:
+			abortWithError(c, NewError(fmt.Sprintf("A request with idempotency key %s is in progress", key), http.StatusConflict))
+			return
+		case ErrIdempotencyKeyMismatch:
+			abortWithError(c, NewError(fmt.Sprintf("Idempotency key %s was used for a different request", key), http.StatusUnprocessableEntity))
+			return
+		default:
+			abortWithError(c, InternalServerError("Failed to reserve idempotency key"))
+			return
+		}
+
+		if stored != nil {
+			replay(c, stored)
+			return
+		}
+
+		// The key is released unless the response is saved, also when the handler panics.
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := store.Release(clientID, key); err != nil {
+				errLog.Sugar().Errorw("Failed to release idempotency key", "requestId", GetRequestID(c), "clientId", clientID, "key", key, "error", err)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer, body: new(bytes.Buffer)}
+		c.Writer = writer
+		c.Next()
+
+		if len(c.Errors) > 0 || writer.Status() >= 500 {
+			return
+		}
+
+		err = store.Save(clientID, key, StoredResponse{
+			StatusCode: writer.Status(),
+			Header:     writer.Header().Clone(),
+			Body:       writer.body.Bytes(),
+		})
+		if err != nil {
+			errLog.Sugar().Errorw("Failed to store idempotent response", "requestId", GetRequestID(c), "clientId", clientID, "key", key, "error", err)
+			return
+		}
+		saved = true
+	}
+}
+
+func replay(c *gin.Context, stored *StoredResponse) {
+	for key, values := range stored.Header {
+		c.Writer.Header()[key] = append([]string(nil), values...)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(stored.StatusCode)
+	c.Writer.Write(stored.Body)
+	c.Abort()
+}
+
+func abortWithError(c *gin.Context, err *Error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// requestFingerprint hashes the method, path and body of a request, restoring the body for the handler.
+func requestFingerprint(c *gin.Context) (string, error) {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// recordingWriter keeps a copy of the response body written by a handler.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	res         *StoredResponse
+	expires     time.Time
+}
+
+type memoryIdempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	nextSweep time.Time
+}
+
+// NewMemoryIdempotencyStore creates an in-memory IdempotencyStore keeping keys for the ttl.
+// Keys are not shared between replicas of a service.
+func NewMemoryIdempotencyStore(ttl time.Duration) IdempotencyStore {
+	return &memoryIdempotencyStore{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+func (s *memoryIdempotencyStore) Reserve(clientID, key, fingerprint string) (*StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.removeExpired(now)
+
+	entry, ok := s.entries[idempotencyID(clientID, key)]
+	if !ok || now.After(entry.expires) {
+		s.entries[idempotencyID(clientID, key)] = &idempotencyEntry{
+			fingerprint: fingerprint,
+			expires:     now.Add(s.ttl),
+		}
+		return nil, nil
+	}
+
+	if entry.fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if entry.res == nil {
+		return nil, ErrIdempotencyKeyInUse
+	}
+
+	return entry.res, nil
+}
+
+func (s *memoryIdempotencyStore) Save(clientID, key string, res StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[idempotencyID(clientID, key)]; ok {
+		entry.res = &res
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(clientID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, idempotencyID(clientID, key))
+	return nil
+}
+
+// removeExpired removes expired keys at most once per ttl. Must be called with the lock held.
+func (s *memoryIdempotencyStore) removeExpired(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+
+	s.nextSweep = now.Add(s.ttl)
+	for id, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, id)
+		}
+	}
+}
+
+func idempotencyID(clientID, key string) string {
+	return clientID + "|" + key
+}
@@ -0,0 +1,134 @@
+package httputil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type sqlIdempotencyStore struct {
+	db      *sql.DB
+	queries map[string]string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewSQLIdempotencyStore creates an IdempotencyStore keeping keys for the ttl in the
+// idempotency_key table, so that keys are shared between replicas of a service.
+// The driver, e.g. dbutil.PostgresConfig.Driver(), decides the placeholder style
+// of the queries. The table is created by the migrations in
+// httputil/resources/idempotency_migrations, or idempotency_migrations_postgres for Postgres.
+func NewSQLIdempotencyStore(db *sql.DB, driver string, ttl time.Duration) IdempotencyStore {
+	queries := make(map[string]string)
+	for _, query := range []string{
+		deleteExpiredIdempotencyKeysQuery,
+		insertIdempotencyKeyQuery,
+		findIdempotencyKeyQuery,
+		saveIdempotentResponseQuery,
+		deleteIdempotencyKeyQuery,
+	} {
+		queries[query] = bindQuery(driver, query)
+	}
+
+	return &sqlIdempotencyStore{
+		db:      db,
+		queries: queries,
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// bindQuery rewrites the ? placeholders of a query to the $n placeholders used by Postgres drivers.
+func bindQuery(driver, query string) string {
+	if driver != "postgres" && driver != "pgx" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+const deleteExpiredIdempotencyKeysQuery = `DELETE FROM idempotency_key WHERE expires_at < ?`
+
+const insertIdempotencyKeyQuery = `
+	INSERT INTO idempotency_key(client_id, idempotency_key, fingerprint, expires_at)
+	VALUES (?, ?, ?, ?)`
+
+const findIdempotencyKeyQuery = `
+	SELECT fingerprint, status_code, header, body FROM idempotency_key
+	WHERE client_id = ? AND idempotency_key = ?`
+
+func (s *sqlIdempotencyStore) Reserve(clientID, key, fingerprint string) (*StoredResponse, error) {
+	now := s.now().UTC()
+	_, err := s.db.Exec(s.queries[deleteExpiredIdempotencyKeysQuery], now)
+	if err != nil {
+		return nil, err
+	}
+
+	_, insertErr := s.db.Exec(s.queries[insertIdempotencyKeyQuery], clientID, key, fingerprint, now.Add(s.ttl))
+	if insertErr == nil {
+		return nil, nil
+	}
+
+	var storedFingerprint string
+	var statusCode sql.NullInt64
+	var header sql.NullString
+	var body []byte
+	err = s.db.QueryRow(s.queries[findIdempotencyKeyQuery], clientID, key).Scan(&storedFingerprint, &statusCode, &header, &body)
+	if err == sql.ErrNoRows {
+		return nil, insertErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if storedFingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if !statusCode.Valid {
+		return nil, ErrIdempotencyKeyInUse
+	}
+
+	res := &StoredResponse{
+		StatusCode: int(statusCode.Int64),
+		Body:       body,
+	}
+	err = json.Unmarshal([]byte(header.String), &res.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+const saveIdempotentResponseQuery = `
+	UPDATE idempotency_key SET status_code = ?, header = ?, body = ?
+	WHERE client_id = ? AND idempotency_key = ?`
+
+func (s *sqlIdempotencyStore) Save(clientID, key string, res StoredResponse) error {
+	header, err := json.Marshal(res.Header)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(s.queries[saveIdempotentResponseQuery], res.StatusCode, string(header), res.Body, clientID, key)
+	return err
+}
+
+const deleteIdempotencyKeyQuery = `DELETE FROM idempotency_key WHERE client_id = ? AND idempotency_key = ?`
+
+func (s *sqlIdempotencyStore) Release(clientID, key string) error {
+	_, err := s.db.Exec(s.queries[deleteIdempotencyKeyQuery], clientID, key)
+	return err
+}
@@ -0,0 +1,16 @@
+package httputil
+
+import "testing"
+
+func TestBindQuery(t *testing.T) {
+	query := `UPDATE idempotency_key SET status_code = ? WHERE client_id = ? AND idempotency_key = ?`
+
+	if bound := bindQuery("sqlite3", query); bound != query {
+		t.Errorf("Expected query to be unchanged for sqlite3, got: %s", bound)
+	}
+
+	expected := `UPDATE idempotency_key SET status_code = $1 WHERE client_id = $2 AND idempotency_key = $3`
+	if bound := bindQuery("postgres", query); bound != expected {
+		t.Errorf("Wrong query for postgres. Expected: %s Got: %s", expected, bound)
+	}
+}
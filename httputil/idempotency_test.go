@@ -0,0 +1,104 @@
+package httputil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/mimir-news/mimir-go/dbutil"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+func TestIdempotencyWithMemoryStore(t *testing.T) {
+	testIdempotency(t, httputil.NewMemoryIdempotencyStore(time.Hour))
+}
+
+func TestIdempotencyWithSQLStore(t *testing.T) {
+	cfg := dbutil.SqliteConfig{}
+	db := dbutil.MustConnect(cfg)
+	defer db.Close()
+
+	err := dbutil.Upgrade("./resources/idempotency_migrations", cfg.Driver(), db)
+	if err != nil {
+		t.Fatal("dbutil.Upgrade returned unexpected error:", err)
+	}
+
+	testIdempotency(t, httputil.NewSQLIdempotencyStore(db, cfg.Driver(), time.Hour))
+}
+
+func testIdempotency(t *testing.T, store httputil.IdempotencyStore) {
+	gin.SetMode(gin.TestMode)
+	r := httputil.NewRouter(func() error { return nil })
+	r.Use(httputil.Idempotency(store))
+
+	created := 0
+	r.POST("/v1/orders", func(c *gin.Context) {
+		created++
+		c.Header("Location", "/v1/orders/1")
+		c.JSON(http.StatusCreated, gin.H{"order": created})
+	})
+	failures := 0
+	r.POST("/v1/failing", func(c *gin.Context) {
+		failures++
+		c.Error(httputil.ServiceUnavailable("Try again"))
+	})
+	panics := 0
+	r.POST("/v1/panicking", func(c *gin.Context) {
+		panics++
+		if panics == 1 {
+			panic("handler failed")
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	send := func(path, clientID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(httputil.ClientIDHeader, clientID)
+		req.Header.Set(httputil.IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("/v1/orders", "client-1", "key-1", `{"symbol":"AAPL"}`)
+	if first.Code != http.StatusCreated || strings.TrimSpace(first.Body.String()) != `{"order":1}` {
+		t.Fatalf("Unexpected first response: %d %q", first.Code, first.Body.String())
+	}
+
+	duplicate := send("/v1/orders", "client-1", "key-1", `{"symbol":"AAPL"}`)
+	if duplicate.Code != http.StatusCreated || strings.TrimSpace(duplicate.Body.String()) != `{"order":1}` {
+		t.Errorf("Duplicate not replayed: %d %s", duplicate.Code, duplicate.Body.String())
+	}
+	if duplicate.Header().Get(httputil.IdempotentReplayedHeader) != "true" || duplicate.Header().Get("Location") != "/v1/orders/1" {
+		t.Errorf("Wrong headers of replayed response: %v", duplicate.Header())
+	}
+	if created != 1 {
+		t.Errorf("Handler should be called once, was called %d times", created)
+	}
+
+	otherClient := send("/v1/orders", "client-2", "key-1", `{"symbol":"AAPL"}`)
+	if otherClient.Code != http.StatusCreated || created != 2 {
+		t.Errorf("Keys should be scoped by client id: %d %s", otherClient.Code, otherClient.Body.String())
+	}
+
+	mismatch := send("/v1/orders", "client-1", "key-1", `{"symbol":"TSLA"}`)
+	if mismatch.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for reused key, got: %d", mismatch.Code)
+	}
+
+	send("/v1/failing", "client-1", "key-2", "")
+	retry := send("/v1/failing", "client-1", "key-2", "")
+	if retry.Code != http.StatusServiceUnavailable || failures != 2 {
+		t.Errorf("Failed requests should not be stored: %d, failures=%d", retry.Code, failures)
+	}
+
+	send("/v1/panicking", "client-1", "key-3", "")
+	retry = send("/v1/panicking", "client-1", "key-3", "")
+	if retry.Code != http.StatusCreated || panics != 2 {
+		t.Errorf("Keys of panicking requests should be released: %d, panics=%d", retry.Code, panics)
+	}
+}
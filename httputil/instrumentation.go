@@ -15,8 +15,10 @@ const metricsPath = "/metrics"
 
 // Header keys
 const (
-	RequestIDHeader = "X-Request-ID"
-	AcceptLanguage  = "Accept-Language"
+	RequestIDHeader      = "X-Request-ID"
+	AcceptLanguage       = "Accept-Language"
+	ClientIDHeader       = "X-ClientID"
+	IdempotencyKeyHeader = "Idempotency-Key"
 )
 
 // Default values
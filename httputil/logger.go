@@ -17,7 +17,10 @@ func Logger() gin.HandlerFunc {
 		start := time.Now()
 		path := c.Request.URL.Path
 		requestID := GetRequestID(c)
-		requestLog.Info(fmt.Sprintf("Incomming request: %s %s", c.Request.Method, path), zap.String("requestId", requestID))
+		traceID := GetTraceID(c)
+		requestLog.Info(fmt.Sprintf("Incomming request: %s %s", c.Request.Method, path),
+			zap.String("requestId", requestID),
+			zap.String("traceId", traceID))
 
 		c.Next()
 
@@ -27,11 +30,12 @@ func Logger() gin.HandlerFunc {
 		requestLog.Info(fmt.Sprintf("Outgoing request: %s %s", c.Request.Method, path),
 			zap.Int("status", c.Writer.Status()),
 			zap.String("requestId", requestID),
+			zap.String("traceId", traceID),
 			zap.Duration("latency", latency))
 	}
 }
 
-// Trace logs a message along with request id.
+// Trace logs a message along with request and trace id.
 func Trace(c *gin.Context, message string) {
-	requestLog.Info(message, zap.String("requestId", GetRequestID(c)))
+	requestLog.Info(message, zap.String("requestId", GetRequestID(c)), zap.String("traceId", GetTraceID(c)))
 }
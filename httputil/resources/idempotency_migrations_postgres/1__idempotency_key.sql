@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE idempotency_key (
+  client_id VARCHAR(100) NOT NULL,
+  idempotency_key VARCHAR(100) NOT NULL,
+  fingerprint VARCHAR(64) NOT NULL,
+  status_code INT NULL,
+  header TEXT NULL,
+  body BYTEA NULL,
+  expires_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (client_id, idempotency_key)
+);
+CREATE INDEX idempotency_key_expires_at_idx ON idempotency_key (expires_at);
+-- +migrate Down
+DROP TABLE IF EXISTS idempotency_key;
@@ -0,0 +1,244 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request signature headers.
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureKeyIDHeader     = "X-Signature-Key-ID"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+)
+
+// DefaultMaxClockSkew is the maximum age of a signed request unless configured.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// SigningKey is a shared secret used to sign the requests of a client.
+type SigningKey struct {
+	ID     string
+	Secret []byte
+}
+
+// KeyStore looks up signing keys by client id.
+type KeyStore interface {
+	// CurrentKey returns the key new requests of a client are signed with.
+	CurrentKey(clientID string) (SigningKey, bool)
+	// Key returns a key of a client by id. Rotated keys stay valid until removed.
+	Key(clientID, keyID string) (SigningKey, bool)
+}
+
+// MemoryKeyStore is an in-memory KeyStore. Keys are rotated by adding a new
+// key, which becomes current, and removing the old key once no longer used.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string][]SigningKey
+}
+
+// NewMemoryKeyStore creates an empty key store.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: make(map[string][]SigningKey),
+	}
+}
+
+// AddKey adds a key of a client and makes it the current key.
+func (s *MemoryKeyStore) AddKey(clientID string, key SigningKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[clientID] = append(s.keys[clientID], key)
+}
+
+// RemoveKey removes a key of a client.
+func (s *MemoryKeyStore) RemoveKey(clientID, keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]SigningKey, 0, len(s.keys[clientID]))
+	for _, key := range s.keys[clientID] {
+		if key.ID != keyID {
+			keys = append(keys, key)
+		}
+	}
+	s.keys[clientID] = keys
+}
+
+// CurrentKey returns the most recently added key of a client.
+func (s *MemoryKeyStore) CurrentKey(clientID string) (SigningKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := s.keys[clientID]
+	if len(keys) == 0 {
+		return SigningKey{}, false
+	}
+	return keys[len(keys)-1], true
+}
+
+// Key returns a key of a client by id.
+func (s *MemoryKeyStore) Key(clientID, keyID string) (SigningKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys[clientID] {
+		if key.ID == keyID {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// NonceStore remembers the nonces of signed requests to reject replays.
+type NonceStore interface {
+	// Use records a nonce of a client for the ttl. Returns false if the nonce has already been used.
+	Use(clientID, nonce string, ttl time.Duration) bool
+}
+
+type memoryNonceStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	nextSweep time.Time
+}
+
+// NewMemoryNonceStore creates an in-memory NonceStore. Nonces are not shared between replicas of a service.
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{
+		now:    time.Now,
+		nonces: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryNonceStore) Use(clientID, nonce string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.nextSweep) {
+		s.nextSweep = now.Add(ttl)
+		for id, expires := range s.nonces {
+			if now.After(expires) {
+				delete(s.nonces, id)
+			}
+		}
+	}
+
+	id := clientID + "|" + nonce
+	if expires, ok := s.nonces[id]; ok && now.Before(expires) {
+		return false
+	}
+
+	s.nonces[id] = now.Add(ttl)
+	return true
+}
+
+// Signature creates the hex encoded HMAC-SHA256 signature of a request from its
+// method, path and query, timestamp, nonce, body and client id.
+func Signature(secret []byte, method, path, timestamp, nonce string, body []byte, clientID string) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		method,
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+		clientID,
+	}, "\n")
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyConfig configures the verification of signed requests.
+type VerifyConfig struct {
+	// Keys is required.
+	Keys KeyStore
+	// Nonces defaults to an in-memory NonceStore.
+	Nonces NonceStore
+	// MaxClockSkew defaults to DefaultMaxClockSkew.
+	MaxClockSkew time.Duration
+}
+
+// VerifySignatures rejects requests without a valid signature of a key of the
+// client in the X-ClientID header with 401. Requests signed outside the clock
+// skew window and requests reusing a nonce are rejected as well.
+func VerifySignatures(cfg VerifyConfig) gin.HandlerFunc {
+	if cfg.Keys == nil {
+		panic("httputil: VerifySignatures requires a KeyStore")
+	}
+	if cfg.Nonces == nil {
+		cfg.Nonces = NewMemoryNonceStore()
+	}
+	if cfg.MaxClockSkew <= 0 {
+		cfg.MaxClockSkew = DefaultMaxClockSkew
+	}
+
+	return func(c *gin.Context) {
+		err := verifySignature(c, cfg, time.Now())
+		if err != nil {
+			errLog.Sugar().Warnw("Rejected request signature", "requestId", GetRequestID(c), "clientId", c.GetHeader(ClientIDHeader), "reason", err.Message)
+			abortWithError(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func verifySignature(c *gin.Context, cfg VerifyConfig, now time.Time) *Error {
+	clientID := c.GetHeader(ClientIDHeader)
+	timestamp := c.GetHeader(SignatureTimestampHeader)
+	nonce := c.GetHeader(SignatureNonceHeader)
+	signature := c.GetHeader(SignatureHeader)
+	if clientID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return Unauthorized("Missing request signature")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Unauthorized("Invalid signature timestamp")
+	}
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > cfg.MaxClockSkew || skew < -cfg.MaxClockSkew {
+		return Unauthorized("Signature timestamp outside of allowed clock skew")
+	}
+
+	key, ok := cfg.Keys.Key(clientID, c.GetHeader(SignatureKeyIDHeader))
+	if !ok {
+		return Unauthorized("Unknown signing key")
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		body, err = ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return BadRequest("Failed to read request body")
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := Signature(key.Secret, c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body, clientID)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return Unauthorized("Invalid request signature")
+	}
+
+	if !cfg.Nonces.Use(clientID, nonce, 2*cfg.MaxClockSkew) {
+		return Unauthorized(fmt.Sprintf("Replayed request nonce %s", nonce))
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package httputil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mimir-news/mimir-go/httputil"
+)
+
+func TestVerifySignatures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := httputil.NewMemoryKeyStore()
+	keys.AddKey("client-1", httputil.SigningKey{ID: "key-1", Secret: []byte("secret-1")})
+
+	r := httputil.NewRouter(func() error { return nil })
+	r.Use(httputil.VerifySignatures(httputil.VerifyConfig{
+		Keys:         keys,
+		Nonces:       httputil.NewMemoryNonceStore(),
+		MaxClockSkew: time.Minute,
+	}))
+	r.POST("/v1/orders", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, string(body))
+	})
+
+	type request struct {
+		keyID     string
+		secret    string
+		timestamp time.Time
+		nonce     string
+		body      string
+		sentBody  string
+	}
+	send := func(sr request) *httptest.ResponseRecorder {
+		timestamp := strconv.FormatInt(sr.timestamp.Unix(), 10)
+		signature := httputil.Signature([]byte(sr.secret), http.MethodPost, "/v1/orders?dryRun=true", timestamp, sr.nonce, []byte(sr.body), "client-1")
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/orders?dryRun=true", strings.NewReader(sr.sentBody))
+		req.Header.Set(httputil.ClientIDHeader, "client-1")
+		req.Header.Set(httputil.SignatureKeyIDHeader, sr.keyID)
+		req.Header.Set(httputil.SignatureTimestampHeader, timestamp)
+		req.Header.Set(httputil.SignatureNonceHeader, sr.nonce)
+		req.Header.Set(httputil.SignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	valid := request{keyID: "key-1", secret: "secret-1", timestamp: time.Now(), nonce: "nonce-1", body: "order", sentBody: "order"}
+	res := send(valid)
+	if res.Code != http.StatusOK || res.Body.String() != "order" {
+		t.Fatalf("Valid signature: expected 200 with body, got %d %s", res.Code, res.Body.String())
+	}
+
+	res = send(valid)
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("Replayed nonce: expected 401, got %d", res.Code)
+	}
+
+	tampered := valid
+	tampered.nonce = "nonce-2"
+	tampered.sentBody = "other order"
+	if res = send(tampered); res.Code != http.StatusUnauthorized {
+		t.Errorf("Tampered body: expected 401, got %d", res.Code)
+	}
+
+	wrongSecret := valid
+	wrongSecret.nonce = "nonce-3"
+	wrongSecret.secret = "secret-2"
+	if res = send(wrongSecret); res.Code != http.StatusUnauthorized {
+		t.Errorf("Wrong secret: expected 401, got %d", res.Code)
+	}
+
+	stale := valid
+	stale.nonce = "nonce-4"
+	stale.timestamp = time.Now().Add(-2 * time.Minute)
+	if res = send(stale); res.Code != http.StatusUnauthorized {
+		t.Errorf("Stale timestamp: expected 401, got %d", res.Code)
+	}
+
+	keys.AddKey("client-1", httputil.SigningKey{ID: "key-2", Secret: []byte("secret-2")})
+	rotated := valid
+	rotated.nonce = "nonce-5"
+	if res = send(rotated); res.Code != http.StatusOK {
+		t.Errorf("Previous key during rotation: expected 200, got %d", res.Code)
+	}
+
+	keys.RemoveKey("client-1", "key-1")
+	removed := valid
+	removed.nonce = "nonce-6"
+	if res = send(removed); res.Code != http.StatusUnauthorized {
+		t.Errorf("Removed key: expected 401, got %d", res.Code)
+	}
+
+	current, ok := keys.CurrentKey("client-1")
+	if !ok || current.ID != "key-2" {
+		t.Errorf("Expected current key to be key-2, got %v %v", current.ID, ok)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader("order"))
+	req.Header.Set(httputil.ClientIDHeader, "client-1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Unsigned request: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestVerifySignaturesDefaults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := httputil.NewMemoryKeyStore()
+	keys.AddKey("client-1", httputil.SigningKey{ID: "key-1", Secret: []byte("secret-1")})
+
+	r := httputil.NewRouter(func() error { return nil })
+	r.Use(httputil.VerifySignatures(httputil.VerifyConfig{Keys: keys}))
+	r.GET("/v1/orders", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	send := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/orders", nil)
+		req.Header.Set(httputil.ClientIDHeader, "client-1")
+		req.Header.Set(httputil.SignatureKeyIDHeader, "key-1")
+		req.Header.Set(httputil.SignatureTimestampHeader, timestamp)
+		req.Header.Set(httputil.SignatureNonceHeader, "nonce-1")
+		req.Header.Set(httputil.SignatureHeader, httputil.Signature([]byte("secret-1"), http.MethodGet, "/v1/orders", timestamp, "nonce-1", nil, "client-1"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send(); code != http.StatusNoContent {
+		t.Errorf("Expected 204 with default nonce store, got %d", code)
+	}
+	if code := send(); code != http.StatusUnauthorized {
+		t.Errorf("Expected replay to be rejected with 401, got %d", code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected VerifySignatures without a KeyStore to panic")
+		}
+	}()
+	httputil.VerifySignatures(httputil.VerifyConfig{})
+}
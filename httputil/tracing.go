@@ -0,0 +1,50 @@
+package httputil
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mimir-news/mimir-go/tracing"
+)
+
+const spanKey = "tracing.span"
+
+// Tracing continues the trace of the caller, or starts a new one, and records a
+// server span of the request using the global tracer. The span is added to the
+// request context, so that http clients called with it propagate the trace.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.URL.Path == metricsPath {
+			c.Next()
+			return
+		}
+
+		parent, _ := tracing.Extract(c.Request.Header)
+		span := tracing.GlobalTracer().StartSpan(c.Request.Method+" "+c.FullPath(), tracing.ServerSpan, parent)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", c.FullPath())
+		span.SetAttribute("request.id", GetRequestID(c))
+
+		c.Set(spanKey, span)
+		c.Request = c.Request.WithContext(tracing.ContextWithSpan(c.Request.Context(), span))
+		c.Header(tracing.TraceparentHeader, span.Context().Traceparent())
+		c.Next()
+
+		span.Finish(c.Writer.Status())
+	}
+}
+
+// GetSpan gets the server span of the request from the gin context, or nil if not traced.
+func GetSpan(c *gin.Context) *tracing.Span {
+	value, _ := c.Get(spanKey)
+	span, _ := value.(*tracing.Span)
+	return span
+}
+
+// GetTraceID gets the trace id of the request from the gin context, or an empty string if not traced.
+func GetTraceID(c *gin.Context) string {
+	span := GetSpan(c)
+	if span == nil {
+		return ""
+	}
+
+	return span.Context().TraceID.String()
+}
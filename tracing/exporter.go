@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"sync"
+
+	"github.com/mimir-news/mimir-go/logger"
+)
+
+var log = logger.GetDefaultLogger("mimir-go/tracing").Sugar()
+
+// Exporter receives finished spans.
+type Exporter interface {
+	Export(span SpanData) error
+}
+
+// InMemoryExporter keeps finished spans in memory, e.g. to assert on them in tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty in-memory exporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{
+		spans: make([]SpanData, 0),
+	}
+}
+
+// Export stores the span.
+func (e *InMemoryExporter) Export(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans returns the spans exported so far, in the order they finished.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset removes all exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = make([]SpanData, 0)
+}
@@ -0,0 +1,280 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default OTLP exporter settings.
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 5 * time.Second
+)
+
+// OTLP span kinds and status codes.
+const (
+	otlpKindServer  = 2
+	otlpKindClient  = 3
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// OTLPConfig configures an OTLP exporter.
+type OTLPConfig struct {
+	// Endpoint is the base url of the collector, spans are posted to Endpoint/v1/traces.
+	Endpoint    string
+	ServiceName string
+	// BatchSize is the number of spans sent in one request, defaults to DefaultBatchSize.
+	BatchSize int
+	// FlushInterval is how often buffered spans are sent, defaults to DefaultFlushInterval.
+	FlushInterval time.Duration
+	HTTPClient    *http.Client
+}
+
+// OTLPExporter sends spans in batches to an OpenTelemetry collector using OTLP/JSON over HTTP.
+type OTLPExporter struct {
+	cfg  OTLPConfig
+	url  string
+	stop chan struct{}
+	done chan struct{}
+
+	mu     sync.Mutex
+	buffer []SpanData
+}
+
+// NewOTLPExporter creates an exporter and starts flushing buffered spans in the background.
+// Shutdown must be called to send the remaining spans and stop the exporter.
+func NewOTLPExporter(cfg OTLPConfig) *OTLPExporter {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	e := &OTLPExporter{
+		cfg:    cfg,
+		url:    strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/traces",
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		buffer: make([]SpanData, 0, cfg.BatchSize),
+	}
+
+	go e.flushPeriodically()
+	return e
+}
+
+// Export buffers the span. Full batches are sent in the background.
+func (e *OTLPExporter) Export(span SpanData) error {
+	e.mu.Lock()
+	e.buffer = append(e.buffer, span)
+	if len(e.buffer) < e.cfg.BatchSize {
+		e.mu.Unlock()
+		return nil
+	}
+
+	batch := e.take()
+	e.mu.Unlock()
+
+	go func() {
+		err := e.send(batch)
+		if err != nil {
+			log.Warnw("Failed to send spans", "spans", len(batch), "error", err)
+		}
+	}()
+	return nil
+}
+
+// Flush sends the buffered spans.
+func (e *OTLPExporter) Flush() error {
+	e.mu.Lock()
+	batch := e.take()
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return e.send(batch)
+}
+
+// Shutdown stops the background flushing and sends the buffered spans.
+func (e *OTLPExporter) Shutdown() error {
+	close(e.stop)
+	<-e.done
+	return e.Flush()
+}
+
+func (e *OTLPExporter) flushPeriodically() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := e.Flush()
+			if err != nil {
+				log.Warnw("Failed to flush spans", "error", err)
+			}
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// take removes and returns the buffered spans. Must be called with the lock held.
+func (e *OTLPExporter) take() []SpanData {
+	batch := e.buffer
+	e.buffer = make([]SpanData, 0, e.cfg.BatchSize)
+	return batch
+}
+
+func (e *OTLPExporter) send(batch []SpanData) error {
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		return err
+	}
+
+	res, err := e.cfg.HTTPClient.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code"`
+}
+
+func (e *OTLPExporter) encode(batch []SpanData) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, data := range batch {
+		spans = append(spans, encodeSpan(data))
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpAttribute{stringAttribute("service.name", e.cfg.ServiceName)},
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: "mimir-go/tracing"},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func encodeSpan(data SpanData) otlpSpan {
+	kind := otlpKindServer
+	if data.Kind == ClientSpan {
+		kind = otlpKindClient
+	}
+
+	status := otlpStatusOK
+	if data.StatusCode == 0 || data.StatusCode >= 500 {
+		status = otlpStatusError
+	}
+
+	attributes := []otlpAttribute{
+		{Key: "http.status_code", Value: otlpValue{IntValue: strconv.Itoa(data.StatusCode)}},
+	}
+	keys := make([]string, 0, len(data.Attributes))
+	for key := range data.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attributes = append(attributes, stringAttribute(key, data.Attributes[key]))
+	}
+
+	span := otlpSpan{
+		TraceID:           data.SpanContext.TraceID.String(),
+		SpanID:            data.SpanContext.SpanID.String(),
+		TraceState:        data.SpanContext.TraceState,
+		Name:              data.Name,
+		Kind:              kind,
+		StartTimeUnixNano: strconv.FormatInt(data.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(data.End.UnixNano(), 10),
+		Attributes:        attributes,
+		Status:            otlpStatus{Code: status},
+	}
+	if data.ParentSpanID.IsValid() {
+		span.ParentSpanID = data.ParentSpanID.String()
+	}
+
+	return span
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
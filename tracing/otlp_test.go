@@ -0,0 +1,71 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mimir-news/mimir-go/tracing"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	requests := make([]map[string]interface{}, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request: %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	exporter := tracing.NewOTLPExporter(tracing.OTLPConfig{
+		Endpoint:      server.URL,
+		ServiceName:   "stock-service",
+		FlushInterval: time.Hour,
+	})
+	tracer := tracing.NewTracer(exporter)
+
+	root := tracer.StartSpan("GET /v1/stocks/:symbol", tracing.ServerSpan, tracing.SpanContext{})
+	child := tracer.StartSpan("GET /v1/prices/:symbol", tracing.ClientSpan, root.Context())
+	child.Finish(200)
+	root.Finish(503)
+
+	err := exporter.Shutdown()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, got: %d", len(requests))
+	}
+
+	resourceSpans := requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	spans := scopeSpans["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got: %d", len(spans))
+	}
+
+	clientSpan := spans[0].(map[string]interface{})
+	serverSpan := spans[1].(map[string]interface{})
+	if clientSpan["traceId"] != root.Context().TraceID.String() || clientSpan["parentSpanId"] != root.Context().SpanID.String() {
+		t.Errorf("Client span not a child of the server span: %v", clientSpan)
+	}
+	if clientSpan["kind"] != float64(3) || serverSpan["kind"] != float64(2) {
+		t.Errorf("Wrong span kinds: %v %v", clientSpan["kind"], serverSpan["kind"])
+	}
+	if serverSpan["status"].(map[string]interface{})["code"] != float64(2) {
+		t.Errorf("Expected error status of server span: %v", serverSpan["status"])
+	}
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship of a span to the remote side of a request.
+type SpanKind int
+
+// Span kinds.
+const (
+	ServerSpan SpanKind = iota
+	ClientSpan
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case ServerSpan:
+		return "server"
+	case ClientSpan:
+		return "client"
+	default:
+		return "unknown"
+	}
+}
+
+// SpanData is a finished span handed to an exporter.
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	Start        time.Time
+	End          time.Time
+	StatusCode   int
+	Attributes   map[string]string
+}
+
+// Duration returns the time between the start and end of the span.
+func (d SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Span records the duration and status of a server or client request.
+type Span struct {
+	tracer *Tracer
+
+	mu       sync.Mutex
+	data     SpanData
+	finished bool
+}
+
+// Context returns the span context propagated to downstream services.
+func (s *Span) Context() SpanContext {
+	return s.data.SpanContext
+}
+
+// SetAttribute sets an attribute of the span.
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// Finish ends the span with the http status of the request and exports it. Only the first call has an effect.
+func (s *Span) Finish(statusCode int) {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+
+	s.finished = true
+	s.data.End = s.tracer.now()
+	s.data.StatusCode = statusCode
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.export(data)
+}
+
+// Tracer starts spans and exports them once finished.
+type Tracer struct {
+	exporter Exporter
+	now      func() time.Time
+}
+
+// NewTracer creates a tracer exporting spans to the exporter. A nil exporter
+// propagates trace context without recording any spans.
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+		now:      time.Now,
+	}
+}
+
+// StartSpan starts a span. If the parent is valid the span joins its trace, otherwise a new trace is started.
+func (t *Tracer) StartSpan(name string, kind SpanKind, parent SpanContext) *Span {
+	sc := SpanContext{
+		TraceID: parent.TraceID,
+		SpanID:  NewSpanID(),
+		Sampled: parent.Sampled,
+	}
+	if parent.IsValid() {
+		sc.TraceState = parent.TraceState
+	} else {
+		sc.TraceID = NewTraceID()
+		sc.Sampled = true
+	}
+
+	return &Span{
+		tracer: t,
+		data: SpanData{
+			Name:         name,
+			Kind:         kind,
+			SpanContext:  sc,
+			ParentSpanID: parent.SpanID,
+			Start:        t.now(),
+			Attributes:   make(map[string]string),
+		},
+	}
+}
+
+func (t *Tracer) export(data SpanData) {
+	if t.exporter == nil || !data.SpanContext.Sampled {
+		return
+	}
+
+	err := t.exporter.Export(data)
+	if err != nil {
+		log.Warnw("Failed to export span", "traceId", data.SpanContext.TraceID, "spanId", data.SpanContext.SpanID, "error", err)
+	}
+}
+
+var (
+	globalMu     sync.RWMutex
+	globalTracer = NewTracer(nil)
+)
+
+// SetGlobalTracer sets the tracer used by the httputil router and by http clients without a tracer of their own.
+func SetGlobalTracer(tracer *Tracer) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalTracer = tracer
+}
+
+// GlobalTracer returns the global tracer, which by default records no spans.
+func GlobalTracer() *Tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalTracer
+}
@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// W3C trace context headers.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+const (
+	traceparentLength = 55
+	sampledFlag       = 0x01
+)
+
+// ErrInvalidTraceparent returned when a traceparent header cannot be parsed.
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// NewTraceID creates a random trace id.
+func NewTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+// NewSpanID creates a random span id.
+func NewSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid checks that the trace id is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid checks that the span id is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext is the part of a span propagated to other services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// IsValid checks that both the trace and span ids are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	var flags byte
+	if sc.Sampled {
+		flags |= sampledFlag
+	}
+
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a traceparent header value, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	value = strings.TrimSpace(value)
+	if len(value) < traceparentLength {
+		return sc, ErrInvalidTraceparent
+	}
+
+	version, err := decodeHex(value[0:2])
+	if err != nil || version[0] == 0xff {
+		return sc, ErrInvalidTraceparent
+	}
+	// Future versions may append fields, version 00 may not.
+	if len(value) > traceparentLength && (version[0] == 0 || value[traceparentLength] != '-') {
+		return sc, ErrInvalidTraceparent
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+
+	traceID, err := decodeHex(value[3:35])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	spanID, err := decodeHex(value[36:52])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	flags, err := decodeHex(value[53:55])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&sampledFlag == sampledFlag
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex, as required by the trace context specification.
+func decodeHex(value string) ([]byte, error) {
+	if strings.ToLower(value) != value {
+		return nil, ErrInvalidTraceparent
+	}
+
+	return hex.DecodeString(value)
+}
+
+// Extract reads the span context of the caller from the trace context headers.
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	sc.TraceState = strings.Join(header[http.CanonicalHeaderKey(TracestateHeader)], ",")
+	return sc, true
+}
+
+// Inject sets the trace context headers of a span context.
+func Inject(sc SpanContext, header http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+
+	header.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(TracestateHeader, sc.TraceState)
+	} else {
+		header.Del(TracestateHeader)
+	}
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying the span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
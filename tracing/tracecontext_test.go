@@ -0,0 +1,94 @@
+package tracing_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mimir-news/mimir-go/tracing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Wrong trace id: %s", sc.TraceID)
+	}
+	if sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("Wrong span id: %s", sc.SpanID)
+	}
+	if !sc.Sampled {
+		t.Error("Span context should be sampled")
+	}
+	if sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("Wrong traceparent: %s", sc.Traceparent())
+	}
+
+	_, err = tracing.ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	if err != nil {
+		t.Errorf("Future versions should be parsed: %s", err)
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+	}
+	for i, value := range invalid {
+		_, err = tracing.ParseTraceparent(value)
+		if err != tracing.ErrInvalidTraceparent {
+			t.Errorf("%d - Expected ErrInvalidTraceparent for [%s], got: %v", i, value, err)
+		}
+	}
+}
+
+func TestExtractAndInject(t *testing.T) {
+	header := make(http.Header)
+	header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Add(tracing.TracestateHeader, "congo=t61rcWkgMzE")
+	header.Add(tracing.TracestateHeader, "rojo=00f067aa0ba902b7")
+
+	parent, ok := tracing.Extract(header)
+	if !ok {
+		t.Fatal("Expected trace context to be extracted")
+	}
+	if parent.TraceState != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Errorf("Wrong tracestate: %s", parent.TraceState)
+	}
+
+	exporter := tracing.NewInMemoryExporter()
+	span := tracing.NewTracer(exporter).StartSpan("GET /v1/stocks", tracing.ClientSpan, parent)
+
+	out := make(http.Header)
+	tracing.Inject(span.Context(), out)
+	child, ok := tracing.Extract(out)
+	if !ok {
+		t.Fatal("Expected injected trace context to be extracted")
+	}
+	if child.TraceID != parent.TraceID || child.SpanID == parent.SpanID {
+		t.Errorf("Child should continue trace %s with a new span, got: %s", parent.TraceID, out.Get(tracing.TraceparentHeader))
+	}
+	if out.Get(tracing.TracestateHeader) != parent.TraceState {
+		t.Errorf("Tracestate not propagated: %s", out.Get(tracing.TracestateHeader))
+	}
+
+	span.Finish(200)
+	span.Finish(500)
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 exported span, got: %d", len(spans))
+	}
+	if spans[0].StatusCode != 200 || spans[0].ParentSpanID != parent.SpanID {
+		t.Errorf("Wrong span exported: %+v", spans[0])
+	}
+
+	if _, ok := tracing.Extract(make(http.Header)); ok {
+		t.Error("Expected no trace context without headers")
+	}
+}